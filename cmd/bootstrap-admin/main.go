@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// bootstrap-admin grants the admin role to the first administrator, who must
+// have signed up already. It refuses once an admin exists; further admins are
+// granted through the admin API.
+func main() {
+	email := flag.String("email", "", "email address of the user to make admin")
+	flag.Parse()
+	if *email == "" {
+		log.Fatal("usage: bootstrap-admin -email <address>")
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	// Granting a role neither sends mail, decrypts tokens nor issues access
+	// tokens.
+	authService := auth.NewService(db.DB(), nil, nil, nil)
+
+	user, err := authService.BootstrapAdmin(context.Background(), *email)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		log.Fatalf("no user with email %q, sign up first", *email)
+	}
+	if errors.Is(err, auth.ErrAdminExists) {
+		log.Fatal("an admin already exists, grant further roles through the admin API")
+	}
+	if err != nil {
+		log.Fatalf("granting admin role: %v", err)
+	}
+
+	log.Printf("Granted the admin role to user %d (%s)", user.ID, user.Email)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database"
+	"huddle-backend/internal/encryption"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// reencrypt-tokens seals every stored OAuth token and TOTP secret with the
+// primary key in TOKEN_ENCRYPTION_KEYS. Run it once after enabling encryption
+// and again after adding a new primary key, before the old key is removed.
+func main() {
+	keyring, err := encryption.KeyringFromEnv()
+	if err != nil {
+		log.Fatalf("token encryption: %v", err)
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	// Re-encrypting neither sends mail nor issues access tokens.
+	authService := auth.NewService(db.DB(), keyring, nil, nil)
+
+	updated, err := authService.ReencryptOAuthTokens(context.Background())
+	if err != nil {
+		log.Fatalf("re-encryption stopped after %d identities: %v", updated, err)
+	}
+
+	log.Printf("Re-encrypted tokens of %d identities with key %q", updated, keyring.PrimaryKeyID())
+
+	updated, err = authService.ReencryptTOTPSecrets(context.Background())
+	if err != nil {
+		log.Fatalf("re-encryption stopped after %d TOTP secrets: %v", updated, err)
+	}
+
+	log.Printf("Re-encrypted TOTP secrets of %d users with key %q", updated, keyring.PrimaryKeyID())
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// Scopes that can be granted to a personal access token. Cookie sessions
+// implicitly hold all of them.
+const (
+	ScopeAccountRead   = "account:read"
+	ScopeProfilesRead  = "profiles:read"
+	ScopeProfilesWrite = "profiles:write"
+	ScopeHuddlesRead   = "huddles:read"
+	ScopeHuddlesWrite  = "huddles:write"
+)
+
+var Scopes = []string{
+	ScopeAccountRead,
+	ScopeProfilesRead,
+	ScopeProfilesWrite,
+	ScopeHuddlesRead,
+	ScopeHuddlesWrite,
+}
+
+const (
+	// AccessTokenPrefix marks personal access tokens so they are easy to
+	// recognise in Authorization headers and secret scanners.
+	AccessTokenPrefix = "hdl_pat_"
+	MaxAccessTokens   = 50
+
+	accessTokenDisplayLength = 12
+)
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrTooManyAccessTokens = fmt.Errorf("a user can have at most %d access tokens", MaxAccessTokens)
+	ErrInvalidScope        = errors.New("invalid scope")
+)
+
+// AccessToken is the identity a request authenticated with a personal access
+// token acts as.
+type AccessToken struct {
+	ID            int32
+	UserID        int32
+	Scopes        []string
+	EmailVerified bool
+}
+
+// ParseScopes checks that every requested scope exists and returns them
+// sorted and without duplicates.
+func ParseScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	slices.Sort(scopes)
+	return scopes, nil
+}
+
+// SplitScopes returns the scopes stored on a personal_access_tokens row.
+func SplitScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
+
+// CreateAccessToken issues a personal access token for the user. The token
+// itself is returned once; only its hash and a short prefix are stored.
+func (s *Service) CreateAccessToken(ctx context.Context, userID int32, name string, scopes []string, expiresAt *time.Time) (string, sqlc.PersonalAccessToken, error) {
+	scopes, err := ParseScopes(scopes)
+	if err != nil {
+		return "", sqlc.PersonalAccessToken{}, err
+	}
+
+	count, err := s.queries.CountUserPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return "", sqlc.PersonalAccessToken{}, fmt.Errorf("error counting access tokens: %w", err)
+	}
+	if count >= MaxAccessTokens {
+		return "", sqlc.PersonalAccessToken{}, ErrTooManyAccessTokens
+	}
+
+	secret, _, err := newToken()
+	if err != nil {
+		return "", sqlc.PersonalAccessToken{}, fmt.Errorf("error generating access token: %w", err)
+	}
+	token := AccessTokenPrefix + secret
+
+	params := sqlc.CreatePersonalAccessTokenParams{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: token[:accessTokenDisplayLength],
+		TokenHash:   hashToken(token),
+		Scopes:      strings.Join(scopes, " "),
+	}
+	if expiresAt != nil {
+		params.ExpiresAt = sql.NullTime{Time: *expiresAt, Valid: true}
+	}
+
+	row, err := s.queries.CreatePersonalAccessToken(ctx, params)
+	if err != nil {
+		return "", sqlc.PersonalAccessToken{}, fmt.Errorf("error creating access token: %w", err)
+	}
+	return token, row, nil
+}
+
+func (s *Service) ListAccessTokens(ctx context.Context, userID int32) ([]sqlc.PersonalAccessToken, error) {
+	tokens, err := s.queries.ListUserPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *Service) RevokeAccessToken(ctx context.Context, userID, tokenID int32) error {
+	deleted, err := s.queries.DeletePersonalAccessToken(ctx, sqlc.DeletePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting access token: %w", err)
+	}
+	if deleted == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateAccessToken resolves a bearer token to the user and scopes it
+// grants, and records that it was used.
+func (s *Service) AuthenticateAccessToken(ctx context.Context, token string) (AccessToken, error) {
+	if !strings.HasPrefix(token, AccessTokenPrefix) {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+
+	row, err := s.queries.GetPersonalAccessTokenByHash(ctx, hashToken(token))
+	if err == sql.ErrNoRows {
+		return AccessToken{}, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("error getting access token: %w", err)
+	}
+	if Suspended(row.SuspendedAt, row.SuspendedUntil, time.Now()) {
+		return AccessToken{}, ErrAccountSuspended
+	}
+	if row.DeletionScheduledAt.Valid {
+		return AccessToken{}, ErrDeletionScheduled
+	}
+
+	// The query only writes when last_used_at is more than a minute old, so
+	// busy scripts do not turn every request into an UPDATE.
+	if err := s.queries.TouchPersonalAccessToken(ctx, row.ID); err != nil {
+		log.Printf("Failed to record access token use: %v", err)
+	}
+
+	return AccessToken{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		Scopes:        SplitScopes(row.Scopes),
+		EmailVerified: row.EmailVerifiedAt.Valid,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{ScopeProfilesWrite, ScopeAccountRead, ScopeProfilesWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{ScopeAccountRead, ScopeProfilesWrite}; !slices.Equal(scopes, want) {
+		t.Fatalf("ParseScopes = %v, want %v", scopes, want)
+	}
+
+	if _, err := ParseScopes([]string{"profiles:delete"}); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected unknown scope to be rejected, got %v", err)
+	}
+	if _, err := ParseScopes(nil); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected empty scopes to be rejected, got %v", err)
+	}
+}
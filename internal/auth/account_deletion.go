@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+)
+
+// Event types recorded around self-service account deletion.
+const (
+	EventDeletionScheduled = "account_deletion_scheduled"
+	EventDeletionCancelled = "account_deletion_cancelled"
+)
+
+// accountDeletionBatch caps how many accounts one PurgeDeletedAccounts run
+// deletes, so a backlog is worked off over several runs.
+const accountDeletionBatch = 100
+
+var ErrDeletionScheduled = errors.New("this account is scheduled for deletion, log in again to cancel it")
+
+// errDeletionCancelled rolls back a purge whose user logged in after the
+// batch was listed.
+var errDeletionCancelled = errors.New("account deletion was cancelled")
+
+// AccountDeletionGrace is how long a deleted account can still be recovered
+// by logging in, from ACCOUNT_DELETION_GRACE (default 30 days).
+func AccountDeletionGrace() time.Duration {
+	return config.Duration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+}
+
+// ScheduleAccountDeletion marks a user's account for deletion once the grace
+// period is over and ends all of their sessions. Until then the account is
+// hidden from profile listings and search, and logging in cancels the
+// deletion. It returns the updated user.
+func (s *Service) ScheduleAccountDeletion(ctx context.Context, userID int32) (sqlc.User, error) {
+	deleteAt := time.Now().Add(AccountDeletionGrace()).UTC()
+
+	err := s.withTx(ctx, func(q *sqlc.Queries) error {
+		scheduled, err := q.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+			ID:                  userID,
+			DeletionScheduledAt: sql.NullTime{Time: deleteAt, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error scheduling deletion: %w", err)
+		}
+		if scheduled == 0 {
+			return ErrUserNotFound
+		}
+		if err := q.DeleteUserSessions(ctx, userID); err != nil {
+			return fmt.Errorf("error deleting sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// SendAccountDeletionEmail tells a user when their account will be deleted
+// and how to keep it.
+func (s *Service) SendAccountDeletionEmail(ctx context.Context, user sqlc.User) error {
+	if !user.DeletionScheduledAt.Valid {
+		return nil
+	}
+	return s.mail.Send(ctx, user.Email, "account_deletion", map[string]string{
+		"Username": user.Username,
+		"DeleteAt": user.DeletionScheduledAt.Time.UTC().Format("January 2, 2006 at 15:04 UTC"),
+		"LoginURL": os.Getenv("FRONTEND_URL") + "/login",
+	})
+}
+
+// cancelAccountDeletion keeps an account that was scheduled for deletion,
+// once its user has fully logged in again.
+func (s *Service) cancelAccountDeletion(ctx context.Context, user sqlc.User, ipAddress, userAgent string) error {
+	if !user.DeletionScheduledAt.Valid {
+		return nil
+	}
+
+	cancelled, err := s.queries.CancelUserDeletion(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("error cancelling account deletion: %w", err)
+	}
+	if cancelled > 0 {
+		s.RecordAuthEvent(ctx, AuthEvent{
+			Type:      EventDeletionCancelled,
+			UserID:    user.ID,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+		})
+	}
+	return nil
+}
+
+// PurgeDeletedAccounts deletes the accounts whose grace period is over and
+// returns how many were removed. Rows in other tables that cascade go with
+// the user; the audit logs keep their entries with the user's email, IP
+// addresses and free-text details scrubbed.
+func (s *Service) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	due, err := s.queries.ListUsersDueForDeletion(ctx, accountDeletionBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing accounts due for deletion: %w", err)
+	}
+
+	var deleted int64
+	for _, user := range due {
+		err := s.withTx(ctx, func(q *sqlc.Queries) error {
+			// Scrub first: once the user is gone, their auth_events rows
+			// no longer point at them.
+			userID := sql.NullInt32{Int32: user.ID, Valid: true}
+			if err := q.ScrubUserAuthEvents(ctx, sqlc.ScrubUserAuthEventsParams{
+				UserID: userID,
+				Email:  sql.NullString{String: user.Email, Valid: true},
+			}); err != nil {
+				return fmt.Errorf("error scrubbing auth events: %w", err)
+			}
+			if err := q.ScrubAdminActionTarget(ctx, userID); err != nil {
+				return fmt.Errorf("error scrubbing admin actions: %w", err)
+			}
+			if err := q.ScrubAdminActionActor(ctx, userID); err != nil {
+				return fmt.Errorf("error scrubbing admin actions: %w", err)
+			}
+
+			removed, err := q.DeleteScheduledUser(ctx, user.ID)
+			if err != nil {
+				return fmt.Errorf("error deleting user: %w", err)
+			}
+			if removed == 0 {
+				return errDeletionCancelled
+			}
+			return nil
+		})
+		if errors.Is(err, errDeletionCancelled) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		log.Printf("Deleted account %d after its grace period", user.ID)
+		deleted++
+	}
+	return deleted, nil
+}
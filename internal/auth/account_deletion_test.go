@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+func TestCheckAccessTokenUser(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	active := createTestUser(t, s, "ada")
+	if err := s.CheckAccessTokenUser(ctx, active.ID); err != nil {
+		t.Fatalf("expected an active user to pass, got %v", err)
+	}
+
+	suspended := createTestUser(t, s, "grace")
+	if err := s.SuspendUser(ctx, suspended.ID, nil, "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckAccessTokenUser(ctx, suspended.ID); !errors.Is(err, ErrAccountSuspended) {
+		t.Fatalf("expected ErrAccountSuspended, got %v", err)
+	}
+
+	leaving := createTestUser(t, s, "linus")
+	if _, err := s.ScheduleAccountDeletion(ctx, leaving.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckAccessTokenUser(ctx, leaving.ID); !errors.Is(err, ErrDeletionScheduled) {
+		t.Fatalf("expected ErrDeletionScheduled, got %v", err)
+	}
+
+	if err := s.CheckAccessTokenUser(ctx, leaving.ID+100); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestScheduleAccountDeletion(t *testing.T) {
+	s, mailer := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	if _, err := s.CreateSession(ctx, user, "password", "192.0.2.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	scheduled, err := s.ScheduleAccountDeletion(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteAt := scheduled.DeletionScheduledAt.Time
+	if !scheduled.DeletionScheduledAt.Valid || deleteAt.Before(before.Add(AccountDeletionGrace()-time.Minute)) {
+		t.Fatalf("expected deletion after the grace period, got %v", scheduled.DeletionScheduledAt)
+	}
+
+	sessions, err := s.ListUserSessions(ctx, user.ID)
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected every session to be ended, got %d: %v", len(sessions), err)
+	}
+
+	if err := s.SendAccountDeletionEmail(ctx, scheduled); err != nil {
+		t.Fatal(err)
+	}
+	if messages := mailer.Messages(); len(messages) != 1 || messages[0].To != user.Email {
+		t.Fatalf("expected a deletion email to %s, got %+v", user.Email, messages)
+	}
+
+	if _, err := s.ScheduleAccountDeletion(ctx, user.ID+100); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestLoginCancelsAccountDeletion(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	scheduled, err := s.ScheduleAccountDeletion(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With two-factor on, the first step of the login is not enough.
+	withTwoFactor := scheduled
+	withTwoFactor.TotpEnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if _, err := s.CreateSession(ctx, withTwoFactor, "password", "192.0.2.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.GetUser(ctx, user.ID); err != nil || !user.DeletionScheduledAt.Valid {
+		t.Fatalf("expected the deletion to wait for the second factor: %v", err)
+	}
+
+	if _, err := s.CreateSession(ctx, scheduled, "password", "192.0.2.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := s.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.DeletionScheduledAt.Valid {
+		t.Fatalf("expected logging in to cancel the deletion, still due at %v", kept.DeletionScheduledAt.Time)
+	}
+
+	events, err := s.ListAuthEvents(ctx, AuthEventFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != EventDeletionCancelled {
+		t.Fatalf("expected one %s event, got %+v", EventDeletionCancelled, events)
+	}
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	leaving := createTestUser(t, s, "ada")
+	later := createTestUser(t, s, "linus")
+	admin := createTestUser(t, s, "grace")
+
+	schedule := func(user sqlc.User, at time.Time) {
+		t.Helper()
+		if _, err := s.queries.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+			ID:                  user.ID,
+			DeletionScheduledAt: sql.NullTime{Time: at, Valid: true},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schedule(leaving, time.Now().Add(-time.Minute))
+	schedule(later, time.Now().Add(time.Hour))
+
+	// A login, and a failed attempt that only names the email.
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginSucceeded, UserID: leaving.ID, Email: leaving.Email, IPAddress: "192.0.2.1", UserAgent: "test"})
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginFailed, Email: leaving.Email, IPAddress: "192.0.2.1", Detail: "bad password"})
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginSucceeded, UserID: later.ID, IPAddress: "192.0.2.2"})
+	// An action on the user, and one the user took as an admin.
+	s.RecordAdminAction(ctx, AdminAction{Action: ActionSuspend, AdminID: admin.ID, TargetUserID: leaving.ID, TargetEmail: leaving.Email, IPAddress: "192.0.2.3"})
+	s.RecordAdminAction(ctx, AdminAction{Action: ActionSuspend, AdminID: leaving.ID, TargetUserID: later.ID, IPAddress: "192.0.2.1", UserAgent: "test"})
+
+	deleted, err := s.PurgeDeletedAccounts(ctx)
+	if err != nil || deleted != 1 {
+		t.Fatalf("PurgeDeletedAccounts deleted %d: %v", deleted, err)
+	}
+	if _, err := s.GetUser(ctx, leaving.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected the account to be deleted, got %v", err)
+	}
+	if _, err := s.GetUser(ctx, later.ID); err != nil {
+		t.Fatalf("expected an account still in its grace period to be kept, got %v", err)
+	}
+
+	events, err := s.ListAuthEvents(ctx, AuthEventFilter{})
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected the audit log to keep 3 events, got %d: %v", len(events), err)
+	}
+	for _, event := range events {
+		if event.UserID.Int32 == later.ID {
+			if event.IpAddress.String != "192.0.2.2" {
+				t.Errorf("expected another user's event to be left alone, got %+v", event)
+			}
+			continue
+		}
+		if event.UserID.Valid || event.Email.Valid || event.IpAddress.Valid || event.UserAgent.Valid || event.Detail.Valid {
+			t.Errorf("expected event %d to be scrubbed, got %+v", event.ID, event)
+		}
+	}
+
+	actions, err := s.ListAdminActions(ctx, AdminActionFilter{})
+	if err != nil || len(actions) != 2 {
+		t.Fatalf("expected the admin trail to keep 2 actions, got %d: %v", len(actions), err)
+	}
+	for _, action := range actions {
+		switch action.AdminID.Int32 {
+		case admin.ID:
+			if action.TargetUserID.Valid || action.TargetEmail.Valid || action.IpAddress.String != "192.0.2.3" {
+				t.Errorf("expected only the target to be scrubbed, got %+v", action)
+			}
+		default:
+			if action.AdminID.Valid || action.IpAddress.Valid || action.UserAgent.Valid || action.TargetUserID.Int32 != later.ID {
+				t.Errorf("expected only the actor to be scrubbed, got %+v", action)
+			}
+		}
+	}
+}
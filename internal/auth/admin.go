@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// Actions recorded in admin_actions.
+const (
+	ActionForceLogout = "force_logout"
+	ActionSuspend     = "suspend"
+	ActionUnsuspend   = "unsuspend"
+	ActionDeleteUser  = "delete_user"
+	ActionGrantRole   = "grant_role"
+	ActionRevokeRole  = "revoke_role"
+
+	ActionImpersonationStarted = "impersonation_started"
+	ActionImpersonationEnded   = "impersonation_ended"
+	// ActionImpersonatedRequest is recorded for every request made while
+	// impersonating.
+	ActionImpersonatedRequest = "impersonated_request"
+)
+
+// MaxUserPage and MaxAdminActionPage cap how many rows one listing returns.
+const (
+	MaxUserPage        = 100
+	MaxAdminActionPage = 200
+)
+
+// AdminAction is one entry of the admin audit trail.
+type AdminAction struct {
+	Action       string
+	AdminID      int32
+	TargetUserID int32
+	TargetEmail  string
+	Detail       string
+	IPAddress    string
+	UserAgent    string
+}
+
+// UserFilter narrows ListUsers. Email and Username match case-insensitive
+// substrings; Provider matches the sign-up provider or any linked identity,
+// and "password" matches users with a password. BeforeID is the pagination
+// cursor.
+type UserFilter struct {
+	Email    string
+	Username string
+	Provider string
+	BeforeID int32
+	Limit    int32
+}
+
+// AdminActionFilter narrows ListAdminActions. Zero fields do not filter.
+type AdminActionFilter struct {
+	AdminID      int32
+	TargetUserID int32
+	BeforeID     int64
+	Limit        int32
+}
+
+// RecordAdminAction appends action to the admin audit trail. Like
+// RecordAuthEvent, failures are logged rather than returned.
+func (s *Service) RecordAdminAction(ctx context.Context, action AdminAction) {
+	ctx = context.WithoutCancel(ctx)
+
+	err := s.queries.CreateAdminAction(ctx, sqlc.CreateAdminActionParams{
+		AdminID:      sql.NullInt32{Int32: action.AdminID, Valid: action.AdminID != 0},
+		TargetUserID: sql.NullInt32{Int32: action.TargetUserID, Valid: action.TargetUserID != 0},
+		TargetEmail:  sql.NullString{String: action.TargetEmail, Valid: action.TargetEmail != ""},
+		Action:       action.Action,
+		Detail:       sql.NullString{String: action.Detail, Valid: action.Detail != ""},
+		IpAddress:    sql.NullString{String: action.IPAddress, Valid: action.IPAddress != ""},
+		UserAgent:    sql.NullString{String: action.UserAgent, Valid: action.UserAgent != ""},
+	})
+	if err != nil {
+		log.Printf("Failed to record %s by admin %d on user %d: %v", action.Action, action.AdminID, action.TargetUserID, err)
+	}
+}
+
+func (s *Service) ListAdminActions(ctx context.Context, filter AdminActionFilter) ([]sqlc.AdminAction, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxAdminActionPage {
+		limit = MaxAdminActionPage
+	}
+
+	actions, err := s.queries.ListAdminActions(ctx, sqlc.ListAdminActionsParams{
+		AdminID:      sql.NullInt32{Int32: filter.AdminID, Valid: filter.AdminID != 0},
+		TargetUserID: sql.NullInt32{Int32: filter.TargetUserID, Valid: filter.TargetUserID != 0},
+		BeforeID:     sql.NullInt64{Int64: filter.BeforeID, Valid: filter.BeforeID != 0},
+		RowLimit:     limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing admin actions: %w", err)
+	}
+	return actions, nil
+}
+
+// ListUsers returns the newest users matching filter.
+func (s *Service) ListUsers(ctx context.Context, filter UserFilter) ([]sqlc.User, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxUserPage {
+		limit = MaxUserPage
+	}
+
+	users, err := s.queries.ListUsers(ctx, sqlc.ListUsersParams{
+		EmailPattern:    containsPattern(filter.Email),
+		UsernamePattern: containsPattern(filter.Username),
+		Provider:        sql.NullString{String: filter.Provider, Valid: filter.Provider != ""},
+		BeforeID:        sql.NullInt32{Int32: filter.BeforeID, Valid: filter.BeforeID != 0},
+		RowLimit:        limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing users: %w", err)
+	}
+	return users, nil
+}
+
+// GetUser returns a user, or ErrUserNotFound.
+func (s *Service) GetUser(ctx context.Context, userID int32) (sqlc.User, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		return sqlc.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
+
+// DeleteUser removes a user and, through the foreign keys, everything they
+// own. It returns the deleted user for the audit trail.
+func (s *Service) DeleteUser(ctx context.Context, userID int32) (sqlc.User, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if err := s.queries.DeleteUser(ctx, userID); err != nil {
+		return sqlc.User{}, fmt.Errorf("error deleting user: %w", err)
+	}
+	return user, nil
+}
+
+// containsPattern builds an ILIKE pattern matching term anywhere, with the
+// wildcards in term escaped.
+func containsPattern(term string) sql.NullString {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return sql.NullString{}
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+	return sql.NullString{String: "%" + escaped + "%", Valid: true}
+}
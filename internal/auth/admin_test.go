@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"ada", "%ada%"},
+		{"  Ada@Example.com ", "%Ada@Example.com%"},
+		{"100%_done", `%100\%\_done%`},
+		{`back\slash`, `%back\\slash%`},
+	}
+	for _, tt := range tests {
+		got := containsPattern(tt.term)
+		if !got.Valid || got.String != tt.want {
+			t.Errorf("containsPattern(%q) = %q, want %q", tt.term, got.String, tt.want)
+		}
+	}
+
+	if got := containsPattern("   "); got.Valid {
+		t.Errorf("expected a blank term not to filter, got %q", got.String)
+	}
+}
+
+func TestListUsersPaging(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	var created []int32
+	for i := range 5 {
+		created = append(created, createTestUser(t, s, fmt.Sprintf("user%d", i)).ID)
+	}
+
+	// Walk the pages the way the admin API does, newest first.
+	var seen []int32
+	filter := UserFilter{Limit: 2}
+	for page := 0; ; page++ {
+		users, err := s.ListUsers(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page > 3 {
+			t.Fatal("paging did not end")
+		}
+		if len(users) == 0 {
+			break
+		}
+		if len(users) > 2 {
+			t.Fatalf("page %d has %d users, limit is 2", page, len(users))
+		}
+		for _, user := range users {
+			seen = append(seen, user.ID)
+		}
+		filter.BeforeID = users[len(users)-1].ID
+	}
+
+	if len(seen) != len(created) {
+		t.Fatalf("paged through %v, want every one of %v", seen, created)
+	}
+	for i, id := range seen {
+		if want := created[len(created)-1-i]; id != want {
+			t.Fatalf("paged through %v, want %v newest first", seen, created)
+		}
+	}
+}
+
+func TestListUsersFilters(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	ada := createTestUser(t, s, "ada")
+	createTestUser(t, s, "grace")
+	withPassword, err := s.queries.CreatePasswordUser(ctx, sqlc.CreatePasswordUserParams{
+		Username:     "linus",
+		Email:        "linus@example.com",
+		PasswordHash: sql.NullString{String: "hash", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter UserFilter
+		want   []int32
+	}{
+		{"username substring", UserFilter{Username: "AD"}, []int32{ada.ID}},
+		{"email substring", UserFilter{Email: "linus@"}, []int32{withPassword.ID}},
+		{"password users", UserFilter{Provider: "password"}, []int32{withPassword.ID}},
+		{"wildcards are literal", UserFilter{Username: "%"}, nil},
+	}
+	for _, tt := range tests {
+		users, err := s.ListUsers(ctx, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int32
+		for _, user := range users {
+			got = append(got, user.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got users %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecordAdminAction(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, s, "grace")
+	target := createTestUser(t, s, "ada")
+
+	s.RecordAdminAction(ctx, AdminAction{
+		Action:       ActionSuspend,
+		AdminID:      admin.ID,
+		TargetUserID: target.ID,
+		TargetEmail:  target.Email,
+		Detail:       "spam",
+		IPAddress:    "192.0.2.1",
+		UserAgent:    "test",
+	})
+	s.RecordAdminAction(ctx, AdminAction{Action: ActionUnsuspend, AdminID: admin.ID, TargetUserID: target.ID})
+	s.RecordAdminAction(ctx, AdminAction{Action: ActionForceLogout, AdminID: target.ID, TargetUserID: admin.ID})
+
+	actions, err := s.ListAdminActions(ctx, AdminActionFilter{AdminID: admin.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Action != ActionUnsuspend || actions[1].Action != ActionSuspend {
+		t.Fatalf("expected the admin's two actions newest first, got %+v", actions)
+	}
+
+	suspend := actions[1]
+	if suspend.TargetUserID.Int32 != target.ID || suspend.TargetEmail.String != target.Email ||
+		suspend.Detail.String != "spam" || suspend.IpAddress.String != "192.0.2.1" || suspend.UserAgent.String != "test" {
+		t.Fatalf("unexpected action %+v", suspend)
+	}
+	if unsuspend := actions[0]; unsuspend.Detail.Valid || unsuspend.IpAddress.Valid || unsuspend.TargetEmail.Valid {
+		t.Fatalf("expected empty fields to be stored as NULL, got %+v", unsuspend)
+	}
+
+	older, err := s.ListAdminActions(ctx, AdminActionFilter{TargetUserID: target.ID, BeforeID: actions[0].ID})
+	if err != nil || len(older) != 1 || older[0].ID != suspend.ID {
+		t.Fatalf("expected the page before %d to hold the suspension, got %+v: %v", actions[0].ID, older, err)
+	}
+}
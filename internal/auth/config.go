@@ -1,7 +1,11 @@
 package auth
 
 import (
+	"database/sql"
 	"os"
+	"time"
+
+	"huddle-backend/internal/config"
 
 	"github.com/gorilla/sessions"
 	"github.com/markbates/goth"
@@ -13,22 +17,77 @@ import (
 const (
 	SessionName  = "huddle_session"
 	SessionIDKey = "session_id"
-	MaxAge       = 86400 * 7 // 7 days
+	// LinkProviderKey marks a pending "connect provider" flow started from a
+	// logged-in session.
+	LinkProviderKey = "link_provider"
+	MaxAge          = 86400 * 7 // 7 days
 )
 
 var Store *sessions.CookieStore
 
+// SessionPolicy controls sliding session expiry. A session lives for
+// IdleTimeout after it was created or last renewed; once RenewAfter (a
+// fraction of IdleTimeout) has elapsed it is renewed on the next request, but
+// never past MaxLifetime from its creation. Sessions waiting for a second
+// factor expire after MFATimeout instead.
+type SessionPolicy struct {
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+	RenewAfter  float64
+	MFATimeout  time.Duration
+}
+
+// renewal returns the expiry a session should slide to at now, or false when
+// it is not past its renewal point yet or has reached MaxLifetime.
+func (p SessionPolicy) renewal(expiresAt time.Time, createdAt sql.NullTime, now time.Time) (time.Time, bool) {
+	renewAt := expiresAt.Add(-time.Duration(float64(p.IdleTimeout) * (1 - p.RenewAfter)))
+	if now.Before(renewAt) {
+		return expiresAt, false
+	}
+
+	renewed := now.Add(p.IdleTimeout)
+	if createdAt.Valid {
+		if deadline := createdAt.Time.Add(p.MaxLifetime); renewed.After(deadline) {
+			renewed = deadline
+		}
+	}
+	if !renewed.After(expiresAt) {
+		return expiresAt, false
+	}
+	return renewed, true
+}
+
+func LoadSessionPolicy() SessionPolicy {
+	policy := SessionPolicy{
+		IdleTimeout: config.Duration("SESSION_IDLE_TIMEOUT", MaxAge*time.Second),
+		MaxLifetime: config.Duration("SESSION_MAX_LIFETIME", 30*24*time.Hour),
+		RenewAfter:  config.Float("SESSION_RENEW_AFTER", 0.5),
+		MFATimeout:  config.Duration("SESSION_MFA_TIMEOUT", 10*time.Minute),
+	}
+
+	if policy.RenewAfter <= 0 || policy.RenewAfter > 1 {
+		policy.RenewAfter = 0.5
+	}
+	if policy.MaxLifetime < policy.IdleTimeout {
+		policy.MaxLifetime = policy.IdleTimeout
+	}
+	return policy
+}
+
 func InitAuth() {
-	sessionSecret := os.Getenv("SESSION_SECRET")
-	if sessionSecret == "" {
-		panic("SESSION_SECRET must be set in production")
+	cookieKeys, err := CookieKeysFromEnv()
+	if err != nil {
+		panic(err)
 	}
 
 	appEnv := os.Getenv("APP_ENV")
 	isProduction := appEnv == "production"
 
-	Store = sessions.NewCookieStore([]byte(sessionSecret))
-	Store.MaxAge(MaxAge)
+	maxAge := int(LoadSessionPolicy().IdleTimeout.Seconds())
+	Store = &sessions.CookieStore{
+		Codecs:  cookieCodecs(cookieKeys, maxAge),
+		Options: &sessions.Options{MaxAge: maxAge},
+	}
 	Store.Options.Path = "/"
 	Store.Options.HttpOnly = true
 	Store.Options.Secure = isProduction
@@ -48,4 +107,5 @@ func InitAuth() {
 			os.Getenv("GITHUB_CALLBACK_URL"),
 		),
 	)
+	goth.UseProviders(oidcProviders()...)
 }
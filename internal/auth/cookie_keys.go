@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+)
+
+const minCookieHashKeyLength = 32
+
+var errRetiredCookieKey = errors.New("session cookie key is retired")
+
+// CookieKey is one signing and encryption key pair of the session cookie.
+// Cookies are written with the first key of a list and read with any key
+// that has not passed its RetireAt time.
+type CookieKey struct {
+	HashKey  []byte
+	BlockKey []byte
+	RetireAt time.Time
+}
+
+// ParseCookieKeys parses SESSION_KEYS: a comma separated list, newest first,
+// of "<hash key>:<encryption key>[:<retire date>]" entries. Keys are standard
+// base64; the hash key must be at least 32 bytes and the encryption key 16, 24
+// or 32 bytes (AES-128, -192 or -256). The retire date is YYYY-MM-DD or
+// RFC 3339.
+func ParseCookieKeys(value string) ([]CookieKey, error) {
+	var keys []CookieKey
+
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("session key %d: expected <hash key>:<encryption key>[:<retire date>]", i+1)
+		}
+
+		hashKey, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("session key %d: invalid hash key: %w", i+1, err)
+		}
+		if len(hashKey) < minCookieHashKeyLength {
+			return nil, fmt.Errorf("session key %d: hash key must be at least %d bytes", i+1, minCookieHashKeyLength)
+		}
+
+		blockKey, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("session key %d: invalid encryption key: %w", i+1, err)
+		}
+		switch len(blockKey) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("session key %d: encryption key must be 16, 24 or 32 bytes", i+1)
+		}
+
+		key := CookieKey{HashKey: hashKey, BlockKey: blockKey}
+		if len(parts) > 2 {
+			// RFC 3339 timestamps contain colons themselves.
+			key.RetireAt, err = parseRetireDate(strings.Join(parts[2:], ":"))
+			if err != nil {
+				return nil, fmt.Errorf("session key %d: %w", i+1, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) > 0 && !keys[0].RetireAt.IsZero() {
+		return nil, errors.New("the first session key writes new cookies and cannot have a retire date")
+	}
+	return keys, nil
+}
+
+func parseRetireDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid retire date %q", value)
+	}
+	return t, nil
+}
+
+// CookieKeysFromEnv reads SESSION_KEYS. SESSION_SECRET, the old single
+// signing secret, is still accepted after those keys, until
+// SESSION_SECRET_RETIRE_AT if set, so that existing cookies survive the
+// switch. On its own it signs cookies without encrypting them.
+func CookieKeysFromEnv() ([]CookieKey, error) {
+	keys, err := ParseCookieKeys(os.Getenv("SESSION_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		key := CookieKey{HashKey: []byte(secret)}
+		if retireAt := os.Getenv("SESSION_SECRET_RETIRE_AT"); retireAt != "" && len(keys) > 0 {
+			key.RetireAt, err = parseRetireDate(retireAt)
+			if err != nil {
+				return nil, fmt.Errorf("SESSION_SECRET_RETIRE_AT: %w", err)
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("SESSION_KEYS or SESSION_SECRET must be set")
+	}
+	if keys[0].BlockKey == nil {
+		log.Printf("Warning: session cookies are signed but not encrypted, set SESSION_KEYS")
+	}
+	return keys, nil
+}
+
+// cookieCodecs builds one codec per key, in order, so that securecookie
+// encodes with the first and decodes with whichever matches.
+func cookieCodecs(keys []CookieKey, maxAge int) []securecookie.Codec {
+	codecs := make([]securecookie.Codec, 0, len(keys))
+	for _, key := range keys {
+		codec := securecookie.New(key.HashKey, key.BlockKey)
+		codec.MaxAge(maxAge)
+
+		if key.RetireAt.IsZero() {
+			codecs = append(codecs, codec)
+		} else {
+			codecs = append(codecs, retiringCodec{Codec: codec, retireAt: key.RetireAt})
+		}
+	}
+	return codecs
+}
+
+// retiringCodec stops accepting cookies once its key is retired, without
+// needing a restart.
+type retiringCodec struct {
+	securecookie.Codec
+	retireAt time.Time
+}
+
+func (c retiringCodec) Decode(name, value string, dst interface{}) error {
+	if time.Now().After(c.retireAt) {
+		return errRetiredCookieKey
+	}
+	return c.Codec.Decode(name, value, dst)
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCookieKey(fill string, size int) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(fill, size)))
+}
+
+func TestParseCookieKeys(t *testing.T) {
+	value := testCookieKey("a", 32) + ":" + testCookieKey("b", 32) + "," +
+		testCookieKey("c", 64) + ":" + testCookieKey("d", 16) + ":2026-01-31"
+
+	keys, err := ParseCookieKeys(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if !keys[0].RetireAt.IsZero() || len(keys[1].BlockKey) != 16 {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+	if want := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC); !keys[1].RetireAt.Equal(want) {
+		t.Fatalf("retire date = %v, want %v", keys[1].RetireAt, want)
+	}
+
+	invalid := []string{
+		testCookieKey("a", 32),
+		testCookieKey("a", 16) + ":" + testCookieKey("b", 32),
+		testCookieKey("a", 32) + ":" + testCookieKey("b", 20),
+		testCookieKey("a", 32) + ":" + testCookieKey("b", 32) + ":someday",
+		testCookieKey("a", 32) + ":" + testCookieKey("b", 32) + ":2026-01-31",
+	}
+	for _, value := range invalid {
+		if _, err := ParseCookieKeys(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestCookieCodecsRotation(t *testing.T) {
+	oldKey := CookieKey{HashKey: []byte(strings.Repeat("o", 32)), BlockKey: []byte(strings.Repeat("p", 32))}
+	newKey := CookieKey{HashKey: []byte(strings.Repeat("n", 32)), BlockKey: []byte(strings.Repeat("m", 32))}
+
+	encoded, err := cookieCodecs([]CookieKey{oldKey}, 3600)[0].Encode(SessionName, map[interface{}]interface{}{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey.RetireAt = time.Now().Add(time.Hour)
+	codecs := cookieCodecs([]CookieKey{newKey, oldKey}, 3600)
+	var values map[interface{}]interface{}
+	if err := codecs[1].Decode(SessionName, encoded, &values); err != nil || values["k"] != "v" {
+		t.Fatalf("expected old key to decode during the grace period, got %v, %v", values, err)
+	}
+	if err := codecs[0].Decode(SessionName, encoded, &values); err == nil {
+		t.Fatal("expected new key to reject a cookie written with the old key")
+	}
+
+	oldKey.RetireAt = time.Now().Add(-time.Hour)
+	codecs = cookieCodecs([]CookieKey{newKey, oldKey}, 3600)
+	if err := codecs[1].Decode(SessionName, encoded, &values); err != errRetiredCookieKey {
+		t.Fatalf("expected retired key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrUsernameTaken      = errors.New("username is already taken")
+)
+
+const (
+	PasswordProvider = "password"
+
+	uniqueViolation = "23505"
+)
+
+// NormalizeEmail trims and lower-cases an email address and checks that it
+// is well formed.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address")
+	}
+	return email, nil
+}
+
+// RegisterWithPassword creates a user who logs in with email and password.
+// Callers validate the input with NormalizeEmail, ValidatePassword and
+// profile.ValidateUsernameFormat first.
+func (s *Service) RegisterWithPassword(ctx context.Context, email, username, password string) (sqlc.User, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	user, err := s.queries.CreatePasswordUser(ctx, sqlc.CreatePasswordUserParams{
+		Username:     username,
+		Email:        email,
+		PasswordHash: sql.NullString{String: hash, Valid: true},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			switch pgErr.ConstraintName {
+			case "users_email_key":
+				return sqlc.User{}, ErrEmailTaken
+			case "users_username_key":
+				return sqlc.User{}, ErrUsernameTaken
+			}
+		}
+		return sqlc.User{}, fmt.Errorf("error creating user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SendAccountExistsEmail tells the owner of email that someone tried to
+// register it again. Registration answers the same way for new and taken
+// emails, so this email is the only place the conflict shows up.
+func (s *Service) SendAccountExistsEmail(ctx context.Context, email string) error {
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	return s.mail.Send(ctx, user.Email, "account_exists", map[string]string{
+		"Username": user.Username,
+		"LoginURL": os.Getenv("FRONTEND_URL") + "/login",
+	})
+}
+
+// AuthenticatePassword returns the user matching email and password. Unknown
+// emails, accounts without a password and wrong passwords all return
+// ErrInvalidCredentials after the same amount of hashing work.
+func (s *Service) AuthenticatePassword(ctx context.Context, email, password string) (sqlc.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if err != nil && err != sql.ErrNoRows {
+		return sqlc.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+	if err == sql.ErrNoRows || !user.PasswordHash.Valid {
+		burnPasswordCheck(password)
+		return sqlc.User{}, ErrInvalidCredentials
+	}
+
+	ok, err := VerifyPassword(password, user.PasswordHash.String)
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("error verifying password: %w", err)
+	}
+	if !ok {
+		return sqlc.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/markbates/goth"
+)
+
+var (
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+)
+
+// SendEmailVerification emails the user a link that verifies their current
+// address. Links sent earlier stop working.
+func (s *Service) SendEmailVerification(ctx context.Context, userID int32) error {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user.EmailVerifiedAt.Valid {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return fmt.Errorf("error generating verification token: %w", err)
+	}
+
+	ttl := config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		if err := q.DeleteUserEmailVerificationTokens(ctx, user.ID); err != nil {
+			return fmt.Errorf("error deleting verification tokens: %w", err)
+		}
+		if _, err := q.CreateEmailVerificationToken(ctx, sqlc.CreateEmailVerificationTokenParams{
+			UserID:    user.ID,
+			Email:     user.Email,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		}); err != nil {
+			return fmt.Errorf("error creating verification token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mail.Send(ctx, user.Email, "verify_email", map[string]string{
+		"Username":  user.Username,
+		"Email":     user.Email,
+		"VerifyURL": os.Getenv("FRONTEND_URL") + "/verify-email?token=" + url.QueryEscape(token),
+		"ExpiresIn": ttl.String(),
+	})
+}
+
+// VerifyEmail redeems a verification token. The token only verifies the
+// address it was sent to, so it is rejected if the user's email has changed
+// since.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		verification, err := q.ConsumeEmailVerificationToken(ctx, hashToken(token))
+		if err == sql.ErrNoRows {
+			return ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return fmt.Errorf("error consuming verification token: %w", err)
+		}
+
+		user, err := q.GetUserByID(ctx, verification.UserID)
+		if err != nil {
+			return fmt.Errorf("error getting user: %w", err)
+		}
+		if user.Email != verification.Email {
+			return ErrInvalidVerificationToken
+		}
+
+		if _, err := q.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{
+			ID:    user.ID,
+			Email: user.Email,
+		}); err != nil {
+			return fmt.Errorf("error verifying email: %w", err)
+		}
+		return nil
+	})
+}
+
+// syncProviderVerifiedEmail marks the user's email as verified when the
+// provider vouches for the same address, and returns the updated user.
+func (s *Service) syncProviderVerifiedEmail(ctx context.Context, user sqlc.User, gothUser goth.User) (sqlc.User, error) {
+	if user.EmailVerifiedAt.Valid || !providerVerifiedEmail(gothUser) || !strings.EqualFold(gothUser.Email, user.Email) {
+		return user, nil
+	}
+
+	updated, err := s.queries.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{
+		ID:    user.ID,
+		Email: user.Email,
+	})
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("error verifying email: %w", err)
+	}
+	if updated == 0 {
+		return user, nil
+	}
+
+	log.Printf("Email of user %d verified by %s", user.ID, gothUser.Provider)
+	return s.queries.GetUserByID(ctx, user.ID)
+}
+
+// PurgeExpiredEmailVerifications deletes used and expired verification
+// tokens and returns how many were removed.
+func (s *Service) PurgeExpiredEmailVerifications(ctx context.Context) (int64, error) {
+	deleted, err := s.queries.DeleteExpiredEmailVerificationTokens(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired verification tokens: %w", err)
+	}
+	return deleted, nil
+}
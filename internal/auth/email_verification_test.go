@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"huddle-backend/internal/mail"
+)
+
+var verifyURLPattern = regexp.MustCompile(`/verify-email\?token=(\S+)`)
+
+// sendTestVerification sends the user a verification email and returns the
+// token from its link.
+func sendTestVerification(t *testing.T, s *Service, mailer *mail.MemoryMailer, userID int32) string {
+	t.Helper()
+	mailer.Reset()
+	if err := s.SendEmailVerification(context.Background(), userID); err != nil {
+		t.Fatal(err)
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected a verification email, got %d", len(messages))
+	}
+	match := verifyURLPattern.FindStringSubmatch(messages[0].Text)
+	if match == nil {
+		t.Fatalf("no verification link in %q", messages[0].Text)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, mailer := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	first := sendTestVerification(t, s, mailer, user.ID)
+	token := sendTestVerification(t, s, mailer, user.ID)
+	if err := s.VerifyEmail(ctx, first); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected an earlier link to stop working, got %v", err)
+	}
+
+	if err := s.VerifyEmail(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := s.queries.GetUserByID(ctx, user.ID)
+	if err != nil || !verified.EmailVerifiedAt.Valid {
+		t.Fatalf("expected the email to be verified, got %+v: %v", verified, err)
+	}
+
+	if err := s.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected the token to work once, got %v", err)
+	}
+	if err := s.SendEmailVerification(ctx, user.ID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Fatalf("expected ErrEmailAlreadyVerified, got %v", err)
+	}
+}
+
+func TestVerifyEmailTokenExpires(t *testing.T) {
+	s, mailer := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	token := sendTestVerification(t, s, mailer, user.ID)
+	if _, err := s.db.ExecContext(ctx, `UPDATE email_verification_tokens SET expires_at = NOW() - INTERVAL '1 minute' WHERE user_id = $1`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+	unverified, err := s.queries.GetUserByID(ctx, user.ID)
+	if err != nil || unverified.EmailVerifiedAt.Valid {
+		t.Fatalf("expected the email to stay unverified, got %+v: %v", unverified, err)
+	}
+
+	if purged, err := s.PurgeExpiredEmailVerifications(ctx); err != nil || purged != 1 {
+		t.Fatalf("expected the expired token to be purged, got %d: %v", purged, err)
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+)
+
+// Event types recorded in auth_events.
+const (
+	EventLoginSucceeded = "login_succeeded"
+	EventLoginFailed    = "login_failed"
+	EventLogout         = "logout"
+	EventSessionRevoked = "session_revoked"
+	EventTokenCreated   = "token_created"
+	EventAccountLocked  = "account_locked"
+
+	EventDataExportRequested  = "data_export_requested"
+	EventDataExportDownloaded = "data_export_downloaded"
+)
+
+// MaxAuthEventPage caps how many events one ListAuthEvents call returns.
+const MaxAuthEventPage = 200
+
+// AuthEvent is one entry of the authentication audit log. UserID is 0 when
+// the account is unknown; failed logins then keep the attempted Email, and
+// RecordAuthEvent attributes them to the account the email belongs to.
+type AuthEvent struct {
+	Type      string
+	UserID    int32
+	Provider  string
+	Email     string
+	IPAddress string
+	UserAgent string
+	Detail    string
+}
+
+// AuthEventFilter narrows ListAuthEvents. Zero fields do not filter. BeforeID
+// is the pagination cursor: the ID of the last event of the previous page.
+type AuthEventFilter struct {
+	UserID    int32
+	IPAddress string
+	Since     time.Time
+	Until     time.Time
+	BeforeID  int64
+	Limit     int32
+}
+
+// RecordAuthEvent appends event to the audit log. Failures are logged rather
+// than returned so that auditing never breaks the request it describes.
+func (s *Service) RecordAuthEvent(ctx context.Context, event AuthEvent) {
+	// The request may be cancelled once its response is written.
+	ctx = context.WithoutCancel(ctx)
+
+	email := strings.ToLower(strings.TrimSpace(event.Email))
+	if event.UserID == 0 && email != "" {
+		if user, err := s.queries.GetUserByEmail(ctx, email); err == nil {
+			event.UserID = user.ID
+		}
+	}
+
+	err := s.queries.CreateAuthEvent(ctx, sqlc.CreateAuthEventParams{
+		UserID:    sql.NullInt32{Int32: event.UserID, Valid: event.UserID != 0},
+		EventType: event.Type,
+		Provider:  sql.NullString{String: event.Provider, Valid: event.Provider != ""},
+		Email:     sql.NullString{String: email, Valid: email != ""},
+		IpAddress: sql.NullString{String: event.IPAddress, Valid: event.IPAddress != ""},
+		UserAgent: sql.NullString{String: event.UserAgent, Valid: event.UserAgent != ""},
+		Detail:    sql.NullString{String: event.Detail, Valid: event.Detail != ""},
+	})
+	if err != nil {
+		log.Printf("Failed to record %s event for user %d: %v", event.Type, event.UserID, err)
+	}
+}
+
+// ListAuthEvents returns the newest events matching filter.
+func (s *Service) ListAuthEvents(ctx context.Context, filter AuthEventFilter) ([]sqlc.AuthEvent, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxAuthEventPage {
+		limit = MaxAuthEventPage
+	}
+
+	events, err := s.queries.ListAuthEvents(ctx, sqlc.ListAuthEventsParams{
+		UserID:    sql.NullInt32{Int32: filter.UserID, Valid: filter.UserID != 0},
+		IpAddress: sql.NullString{String: filter.IPAddress, Valid: filter.IPAddress != ""},
+		Since:     sql.NullTime{Time: filter.Since, Valid: !filter.Since.IsZero()},
+		Until:     sql.NullTime{Time: filter.Until, Valid: !filter.Until.IsZero()},
+		BeforeID:  sql.NullInt64{Int64: filter.BeforeID, Valid: filter.BeforeID != 0},
+		RowLimit:  limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing auth events: %w", err)
+	}
+	return events, nil
+}
+
+// PurgeOldAuthEvents removes events older than AUTH_EVENT_RETENTION.
+func (s *Service) PurgeOldAuthEvents(ctx context.Context) (int64, error) {
+	retention := config.Duration("AUTH_EVENT_RETENTION", 90*24*time.Hour)
+
+	deleted, err := s.queries.DeleteAuthEventsBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old auth events: %w", err)
+	}
+	return deleted, nil
+}
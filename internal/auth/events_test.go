@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordAuthEvent(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	// Failed logins only know the email that was typed in.
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginFailed, Email: "  ADA@example.com ", IPAddress: "192.0.2.1", Detail: "bad password"})
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginFailed, Email: "nobody@example.com", IPAddress: "192.0.2.1"})
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLogout, UserID: user.ID})
+
+	events, err := s.ListAuthEvents(ctx, AuthEventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	logout, unknown, failed := events[0], events[1], events[2]
+	if failed.UserID.Int32 != user.ID || failed.Email.String != "ada@example.com" || failed.Detail.String != "bad password" {
+		t.Errorf("expected the email to be normalized and matched to the user, got %+v", failed)
+	}
+	if unknown.UserID.Valid || unknown.Email.String != "nobody@example.com" {
+		t.Errorf("expected an unknown email to be kept without a user, got %+v", unknown)
+	}
+	if logout.UserID.Int32 != user.ID || logout.Email.Valid || logout.IpAddress.Valid || logout.Provider.Valid {
+		t.Errorf("expected empty fields to be stored as NULL, got %+v", logout)
+	}
+}
+
+func TestListAuthEventsPaging(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	ada := createTestUser(t, s, "ada")
+	grace := createTestUser(t, s, "grace")
+
+	for range 5 {
+		s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginSucceeded, UserID: ada.ID, IPAddress: "192.0.2.1"})
+	}
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginSucceeded, UserID: grace.ID, IPAddress: "192.0.2.2"})
+
+	var seen []int64
+	filter := AuthEventFilter{UserID: ada.ID, Limit: 2}
+	for page := 0; ; page++ {
+		events, err := s.ListAuthEvents(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page > 3 {
+			t.Fatal("paging did not end")
+		}
+		if len(events) == 0 {
+			break
+		}
+		for _, event := range events {
+			if event.UserID.Int32 != ada.ID {
+				t.Fatalf("filter by user returned %+v", event)
+			}
+			if len(seen) > 0 && event.ID >= seen[len(seen)-1] {
+				t.Fatalf("expected events newest first, got %d after %d", event.ID, seen[len(seen)-1])
+			}
+			seen = append(seen, event.ID)
+		}
+		filter.BeforeID = events[len(events)-1].ID
+	}
+	if len(seen) != 5 {
+		t.Fatalf("paged through %d events, want 5", len(seen))
+	}
+
+	byIP, err := s.ListAuthEvents(ctx, AuthEventFilter{IPAddress: "192.0.2.2"})
+	if err != nil || len(byIP) != 1 || byIP[0].UserID.Int32 != grace.ID {
+		t.Fatalf("expected one event from 192.0.2.2, got %+v: %v", byIP, err)
+	}
+
+	future, err := s.ListAuthEvents(ctx, AuthEventFilter{Since: time.Now().Add(time.Hour)})
+	if err != nil || len(future) != 0 {
+		t.Fatalf("expected no events in the future, got %d: %v", len(future), err)
+	}
+}
+
+func TestPurgeOldAuthEvents(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLoginSucceeded, UserID: user.ID})
+	s.RecordAuthEvent(ctx, AuthEvent{Type: EventLogout, UserID: user.ID})
+	events, err := s.ListAuthEvents(ctx, AuthEventFilter{})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %v", len(events), err)
+	}
+
+	// Age the login past the default retention of 90 days.
+	if _, err := s.db.ExecContext(ctx, `UPDATE auth_events SET created_at = created_at - INTERVAL '91 days' WHERE id = $1`, events[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := s.PurgeOldAuthEvents(ctx)
+	if err != nil || deleted != 1 {
+		t.Fatalf("PurgeOldAuthEvents deleted %d: %v", deleted, err)
+	}
+	kept, err := s.ListAuthEvents(ctx, AuthEventFilter{})
+	if err != nil || len(kept) != 1 || kept[0].EventType != EventLogout {
+		t.Fatalf("expected the recent event to be kept, got %+v: %v", kept, err)
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/markbates/goth"
+)
+
+// providerVerifiedEmail reports whether the provider vouches that the user
+// owns gothUser.Email. Only verified emails are used to link a new identity to
+// an existing account.
+func providerVerifiedEmail(gothUser goth.User) bool {
+	if gothUser.Email == "" {
+		return false
+	}
+
+	for _, claim := range []string{"email_verified", "verified_email"} {
+		switch verified := gothUser.RawData[claim].(type) {
+		case bool:
+			return verified
+		case string:
+			return verified == "true"
+		}
+	}
+
+	// GitHub only exposes verified addresses, both as the public profile
+	// email and as the primary email goth falls back to.
+	return gothUser.Provider == "github"
+}
+
+func (s *Service) createIdentity(ctx context.Context, q *sqlc.Queries, userID int32, gothUser goth.User) (sqlc.UserIdentity, error) {
+	accessToken, err := s.encryptToken(gothUser.AccessToken)
+	if err != nil {
+		return sqlc.UserIdentity{}, err
+	}
+	refreshToken, err := s.encryptToken(gothUser.RefreshToken)
+	if err != nil {
+		return sqlc.UserIdentity{}, err
+	}
+
+	identity, err := q.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:         userID,
+		Provider:       gothUser.Provider,
+		ProviderUserID: gothUser.UserID,
+		Email:          sql.NullString{String: gothUser.Email, Valid: gothUser.Email != ""},
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		ExpiresAt:      sql.NullTime{Time: gothUser.ExpiresAt, Valid: !gothUser.ExpiresAt.IsZero()},
+	})
+	if err != nil {
+		return sqlc.UserIdentity{}, fmt.Errorf("error creating identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (s *Service) updateIdentityTokens(ctx context.Context, identityID int32, gothUser goth.User) (sqlc.UserIdentity, error) {
+	accessToken, err := s.encryptToken(gothUser.AccessToken)
+	if err != nil {
+		return sqlc.UserIdentity{}, err
+	}
+	refreshToken, err := s.encryptToken(gothUser.RefreshToken)
+	if err != nil {
+		return sqlc.UserIdentity{}, err
+	}
+
+	identity, err := s.queries.UpdateUserIdentityTokens(ctx, sqlc.UpdateUserIdentityTokensParams{
+		ID:           identityID,
+		Email:        sql.NullString{String: gothUser.Email, Valid: gothUser.Email != ""},
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    sql.NullTime{Time: gothUser.ExpiresAt, Valid: !gothUser.ExpiresAt.IsZero()},
+	})
+	if err != nil {
+		return sqlc.UserIdentity{}, fmt.Errorf("error updating identity tokens: %w", err)
+	}
+	return identity, nil
+}
+
+// LinkOAuthIdentity connects gothUser to the account of userID, as requested
+// from a logged-in session.
+func (s *Service) LinkOAuthIdentity(ctx context.Context, userID int32, gothUser goth.User) (sqlc.UserIdentity, error) {
+	identity, err := s.queries.GetUserIdentityByProvider(ctx, sqlc.GetUserIdentityByProviderParams{
+		Provider:       gothUser.Provider,
+		ProviderUserID: gothUser.UserID,
+	})
+	if err == nil {
+		if identity.UserID != userID {
+			return sqlc.UserIdentity{}, ErrIdentityInUse
+		}
+		return s.updateIdentityTokens(ctx, identity.ID, gothUser)
+	}
+	if err != sql.ErrNoRows {
+		return sqlc.UserIdentity{}, fmt.Errorf("error checking existing identity: %w", err)
+	}
+
+	return s.createIdentity(ctx, s.queries, userID, gothUser)
+}
+
+func (s *Service) ListIdentities(ctx context.Context, userID int32) ([]sqlc.UserIdentity, error) {
+	identities, err := s.queries.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing identities: %w", err)
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes one of the user's identities, refusing to remove the
+// last way the user can log in.
+func (s *Service) UnlinkIdentity(ctx context.Context, userID, identityID int32) error {
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		// Locking the user makes concurrent unlinks wait for each other, so
+		// two requests cannot each remove one of the last two identities.
+		user, err := q.LockUserByID(ctx, userID)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+
+		identities, err := q.ListUserIdentities(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("error listing identities: %w", err)
+		}
+		found := false
+		for _, identity := range identities {
+			if identity.ID == identityID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrIdentityNotFound
+		}
+		if len(identities) == 1 && !user.PasswordHash.Valid {
+			return ErrLastLoginMethod
+		}
+
+		if _, err := q.DeleteUserIdentity(ctx, sqlc.DeleteUserIdentityParams{
+			ID:     identityID,
+			UserID: userID,
+		}); err != nil {
+			return fmt.Errorf("error deleting identity: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *Service) encryptToken(token string) (sql.NullString, error) {
+	ciphertext, err := s.keyring.Encrypt(token)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("error encrypting oauth token: %w", err)
+	}
+	return sql.NullString{String: ciphertext, Valid: true}, nil
+}
+
+// OAuthTokens returns the decrypted provider access and refresh tokens of an
+// identity.
+func (s *Service) OAuthTokens(identity sqlc.UserIdentity) (string, string, error) {
+	accessToken, err := s.keyring.Decrypt(identity.AccessToken.String)
+	if err != nil {
+		return "", "", fmt.Errorf("error decrypting access token: %w", err)
+	}
+	refreshToken, err := s.keyring.Decrypt(identity.RefreshToken.String)
+	if err != nil {
+		return "", "", fmt.Errorf("error decrypting refresh token: %w", err)
+	}
+	return accessToken, refreshToken, nil
+}
+
+// ReencryptOAuthTokens rewrites every stored provider token that is still
+// plaintext or sealed with a non-primary key, and returns how many identities
+// were updated. It is safe to run repeatedly.
+func (s *Service) ReencryptOAuthTokens(ctx context.Context) (int, error) {
+	updated := 0
+	var lastID int32
+
+	for {
+		identities, err := s.queries.ListUserIdentitiesWithOAuthTokens(ctx, sqlc.ListUserIdentitiesWithOAuthTokensParams{
+			ID:    lastID,
+			Limit: 100,
+		})
+		if err != nil {
+			return updated, fmt.Errorf("error listing identities: %w", err)
+		}
+		if len(identities) == 0 {
+			return updated, nil
+		}
+
+		for _, identity := range identities {
+			lastID = identity.ID
+
+			if !s.keyring.NeedsRotation(identity.AccessToken.String) && !s.keyring.NeedsRotation(identity.RefreshToken.String) {
+				continue
+			}
+
+			accessToken, refreshToken, err := s.OAuthTokens(identity)
+			if err != nil {
+				return updated, fmt.Errorf("identity %d: %w", identity.ID, err)
+			}
+			encryptedAccess, err := s.encryptToken(accessToken)
+			if err != nil {
+				return updated, err
+			}
+			encryptedRefresh, err := s.encryptToken(refreshToken)
+			if err != nil {
+				return updated, err
+			}
+
+			if _, err := s.queries.UpdateUserIdentityTokens(ctx, sqlc.UpdateUserIdentityTokensParams{
+				ID:           identity.ID,
+				Email:        identity.Email,
+				AccessToken:  sql.NullString{String: encryptedAccess.String, Valid: identity.AccessToken.Valid},
+				RefreshToken: sql.NullString{String: encryptedRefresh.String, Valid: identity.RefreshToken.Valid},
+				ExpiresAt:    identity.ExpiresAt,
+			}); err != nil {
+				return updated, fmt.Errorf("error updating identity %d: %w", identity.ID, err)
+			}
+			updated++
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/markbates/goth"
+)
+
+func TestFindOrCreateOAuthUserLowercasesEmail(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	ada := createTestUser(t, s, "ada")
+	if _, err := s.queries.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{ID: ada.ID, Email: ada.Email}); err != nil {
+		t.Fatal(err)
+	}
+	linked, err := s.FindOrCreateOAuthUser(ctx, goth.User{
+		Provider: "keycloak",
+		UserID:   "ada-1",
+		Email:    "Ada@Example.COM",
+		RawData:  map[string]any{"email_verified": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.ID != ada.ID {
+		t.Fatalf("expected the identity to be linked to user %d, got user %d", ada.ID, linked.ID)
+	}
+
+	created, err := s.FindOrCreateOAuthUser(ctx, goth.User{
+		Provider: "keycloak",
+		UserID:   "grace-1",
+		Email:    " Grace@Example.com",
+		NickName: "grace",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Email != "grace@example.com" {
+		t.Fatalf("expected the email to be stored lower-cased, got %q", created.Email)
+	}
+}
+
+// TestUnlinkIdentityKeepsLastLoginMethod races unlinks of both identities of
+// an account without a password; one of them has to stay.
+func TestUnlinkIdentityKeepsLastLoginMethod(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	var identities []sqlc.UserIdentity
+	for _, provider := range []string{"google", "github"} {
+		identity, err := s.queries.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+			UserID:         user.ID,
+			Provider:       provider,
+			ProviderUserID: "ada-1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		identities = append(identities, identity)
+	}
+
+	errs := make([]error, len(identities))
+	var wg sync.WaitGroup
+	for i, identity := range identities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.UnlinkIdentity(ctx, user.ID, identity.ID)
+		}()
+	}
+	wg.Wait()
+
+	var unlinked, refused int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			unlinked++
+		case errors.Is(err, ErrLastLoginMethod):
+			refused++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if unlinked != 1 || refused != 1 {
+		t.Fatalf("expected one unlink and one ErrLastLoginMethod, got %v", errs)
+	}
+
+	left, err := s.ListIdentities(ctx, user.ID)
+	if err != nil || len(left) != 1 {
+		t.Fatalf("expected one identity to be left, got %d: %v", len(left), err)
+	}
+	if err := s.UnlinkIdentity(ctx, user.ID, identities[0].ID+identities[1].ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Fatalf("expected ErrIdentityNotFound, got %v", err)
+	}
+}
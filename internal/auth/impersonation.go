@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// ImpersonationProvider is the provider recorded on impersonation
+	// sessions.
+	ImpersonationProvider = "impersonation"
+
+	// ImpersonatorSessionIDKey keeps the admin's own session in the cookie
+	// while impersonating, so that ending the impersonation restores it.
+	ImpersonatorSessionIDKey = "impersonator_session_id"
+
+	// ImpersonatorIDKey and ImpersonatedUserIDKey name the admin and the
+	// user in the cookie, so that requests are audited and the impersonation
+	// can be ended even once its session is gone.
+	ImpersonatorIDKey     = "impersonator_id"
+	ImpersonatedUserIDKey = "impersonated_user_id"
+)
+
+var (
+	ErrImpersonateSelf  = errors.New("you cannot impersonate yourself")
+	ErrNotImpersonating = errors.New("this session is not impersonating anyone")
+	ErrImpersonating    = errors.New("this cannot be done while impersonating a user")
+	// ErrImpersonationRevoked is returned when the admin behind an
+	// impersonation was suspended or lost users:impersonate.
+	ErrImpersonationRevoked = errors.New("the impersonating admin may no longer impersonate users")
+)
+
+// Impersonation describes an impersonation session: ImpersonatorID is logged
+// in as the session's user until ExpiresAt.
+type Impersonation struct {
+	ImpersonatorID int32     `json:"impersonator_id"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// CookieImpersonation reads the impersonation the impersonate endpoint
+// stored in a huddle_session cookie. ok is false when the cookie is not
+// impersonating anyone.
+func CookieImpersonation(cookieSession *sessions.Session) (impersonatorID, userID int32, ok bool) {
+	impersonatorID, ok = cookieSession.Values[ImpersonatorIDKey].(int32)
+	if !ok {
+		return 0, 0, false
+	}
+	userID, ok = cookieSession.Values[ImpersonatedUserIDKey].(int32)
+	return impersonatorID, userID, ok
+}
+
+// ImpersonationTTL is how long an impersonation session lasts, from
+// IMPERSONATION_TTL (default 30m). It is never renewed.
+func ImpersonationTTL() time.Duration {
+	return config.Duration("IMPERSONATION_TTL", 30*time.Minute)
+}
+
+// StartImpersonation creates a session that logs impersonatorID in as
+// targetID. The session holds none of the target's roles, so impersonating
+// an admin grants no admin access.
+func (s *Service) StartImpersonation(ctx context.Context, impersonatorID, targetID int32, ipAddress, userAgent string) (sqlc.Session, error) {
+	if impersonatorID == targetID {
+		return sqlc.Session{}, ErrImpersonateSelf
+	}
+
+	target, err := s.GetUser(ctx, targetID)
+	if err != nil {
+		return sqlc.Session{}, err
+	}
+	if err := CheckSuspension(target); err != nil {
+		return sqlc.Session{}, err
+	}
+
+	sessionID, err := GenerateSessionID()
+	if err != nil {
+		return sqlc.Session{}, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	session, err := s.queries.CreateImpersonationSession(ctx, sqlc.CreateImpersonationSessionParams{
+		ID:             sessionID,
+		UserID:         targetID,
+		ImpersonatorID: sql.NullInt32{Int32: impersonatorID, Valid: true},
+		Provider:       sql.NullString{String: ImpersonationProvider, Valid: true},
+		IpAddress:      sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		UserAgent:      sql.NullString{String: userAgent, Valid: userAgent != ""},
+		ExpiresAt:      time.Now().Add(ImpersonationTTL()),
+	})
+	if err != nil {
+		return sqlc.Session{}, fmt.Errorf("error creating impersonation session: %w", err)
+	}
+	return session, nil
+}
+
+// CheckImpersonator makes sure the admin behind an impersonation session is
+// still allowed to impersonate: not suspended, and still granted
+// users:impersonate. Revoking either ends their impersonations already; this
+// holds the line should one slip through.
+func (s *Service) CheckImpersonator(ctx context.Context, impersonatorID int32) error {
+	if err := s.UserSuspension(ctx, impersonatorID); err != nil {
+		if errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrUserNotFound) {
+			return ErrImpersonationRevoked
+		}
+		return err
+	}
+	grants, err := s.UserGrants(ctx, impersonatorID)
+	if err != nil {
+		return err
+	}
+	if !grants.Can(PermissionUsersImpersonate) {
+		return ErrImpersonationRevoked
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// TestCookieImpersonation round-trips the impersonation through a signed
+// cookie, the way the impersonate and end endpoints use it.
+func TestCookieImpersonation(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/7/impersonate", nil)
+	cookieSession, _ := store.Get(req, SessionName)
+	if _, _, ok := CookieImpersonation(cookieSession); ok {
+		t.Fatal("expected an empty cookie not to be impersonating")
+	}
+	cookieSession.Values[SessionIDKey] = "impersonation-session"
+	cookieSession.Values[ImpersonatorSessionIDKey] = "admin-session"
+	cookieSession.Values[ImpersonatorIDKey] = int32(1)
+	cookieSession.Values[ImpersonatedUserIDKey] = int32(7)
+
+	rec := httptest.NewRecorder()
+	if err := cookieSession.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	next := httptest.NewRequest(http.MethodPost, "/api/impersonation/end", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		next.AddCookie(cookie)
+	}
+	decoded, err := store.Get(next, SessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impersonatorID, userID, ok := CookieImpersonation(decoded)
+	if !ok || impersonatorID != 1 || userID != 7 {
+		t.Fatalf("got impersonator %d, user %d, ok %v", impersonatorID, userID, ok)
+	}
+}
+
+// TestUserSessionsHideImpersonation checks that the target of an
+// impersonation can neither see nor end it from their session settings, and
+// that half-finished two-factor logins are not listed as devices.
+func TestUserSessionsHideImpersonation(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, s, "admin")
+	target := createTestUser(t, s, "ada")
+
+	own, err := s.CreateSession(ctx, target, PasswordProvider, "192.0.2.1", "ada-browser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTwoFactor := target
+	withTwoFactor.TotpEnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	pending, err := s.CreateSession(ctx, withTwoFactor, PasswordProvider, "192.0.2.2", "ada-phone")
+	if err != nil || !pending.MfaPending {
+		t.Fatalf("expected a pending session, got %+v: %v", pending, err)
+	}
+	impersonation, err := s.StartImpersonation(ctx, admin.ID, target.ID, "203.0.113.9", "admin-browser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := s.ListUserSessions(ctx, target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != own.ID {
+		t.Fatalf("expected only the user's own session, got %+v", listed)
+	}
+
+	for _, hidden := range []string{impersonation.ID, pending.ID} {
+		if err := s.RevokeUserSession(ctx, target.ID, SessionHandle(hidden)); !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("expected ErrSessionNotFound, got %v", err)
+		}
+	}
+
+	revoked, err := s.RevokeOtherUserSessions(ctx, target.ID, own.ID)
+	if err != nil || revoked != 1 {
+		t.Fatalf("expected the pending login to be revoked, revoked %d: %v", revoked, err)
+	}
+	if _, err := s.GetSessionByID(ctx, impersonation.ID); err != nil {
+		t.Fatalf("expected the impersonation to survive, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/config"
+)
+
+const (
+	jwtIssuer       = "huddle"
+	jwtHeader       = `{"alg":"HS256","typ":"JWT"}`
+	jwtMinKeyLength = 32
+	jwtLeeway       = 30 * time.Second
+)
+
+var ErrInvalidJWT = errors.New("invalid or expired access token")
+
+// AccessClaims are the claims of a JWT access token. They carry everything
+// RequireAuth needs apart from the user's suspension status.
+type AccessClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	SessionID     string   `json:"sid"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles,omitempty"`
+	Permissions   []string `json:"perms,omitempty"`
+	IssuedAt      int64    `json:"iat"`
+	ExpiresAt     int64    `json:"exp"`
+}
+
+// UserID returns the user the token was issued to.
+func (c AccessClaims) UserID() (int32, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidJWT
+	}
+	return int32(id), nil
+}
+
+// Grants returns the roles and permissions the token was issued with.
+func (c AccessClaims) Grants() Grants {
+	return Grants{Roles: c.Roles, Permissions: c.Permissions}
+}
+
+// JWTSigner issues and verifies HS256 access tokens.
+type JWTSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewJWTSigner(key []byte, ttl time.Duration) (*JWTSigner, error) {
+	if len(key) < jwtMinKeyLength {
+		return nil, fmt.Errorf("jwt signing key must be at least %d bytes", jwtMinKeyLength)
+	}
+	return &JWTSigner{key: key, ttl: ttl}, nil
+}
+
+// JWTSignerFromEnv builds the signer from JWT_SIGNING_KEY and
+// JWT_ACCESS_TTL (default 15m).
+func JWTSignerFromEnv() (*JWTSigner, error) {
+	key := os.Getenv("JWT_SIGNING_KEY")
+	if key == "" {
+		return nil, errors.New("JWT_SIGNING_KEY must be set")
+	}
+	return NewJWTSigner([]byte(key), config.Duration("JWT_ACCESS_TTL", 15*time.Minute))
+}
+
+// TTL is how long issued access tokens stay valid.
+func (j *JWTSigner) TTL() time.Duration {
+	return j.ttl
+}
+
+// Sign issues an access token for userID, valid from now for the signer's TTL.
+// The user's grants are embedded, so a revoked role lasts until the token
+// expires.
+func (j *JWTSigner) Sign(userID int32, sessionID string, emailVerified bool, grants Grants, now time.Time) (string, error) {
+	claims := AccessClaims{
+		Issuer:        jwtIssuer,
+		Subject:       strconv.Itoa(int(userID)),
+		SessionID:     sessionID,
+		EmailVerified: emailVerified,
+		Roles:         grants.Roles,
+		Permissions:   grants.Permissions,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(j.ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("error encoding claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(jwtHeader)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(j.sign(signingInput)), nil
+}
+
+// Verify checks the signature, algorithm, issuer and expiry of token and
+// returns its claims.
+func (j *JWTSigner) Verify(token string, now time.Time) (AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+
+	// Only HS256 is accepted, whatever the header claims, so tokens cannot
+	// downgrade to "none" or switch algorithms.
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+	var claims AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+
+	if claims.Issuer != jwtIssuer || now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return AccessClaims{}, ErrInvalidJWT
+	}
+	return claims, nil
+}
+
+func (j *JWTSigner) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, j.key)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
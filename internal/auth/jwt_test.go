@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T) *JWTSigner {
+	t.Helper()
+
+	signer, err := NewJWTSigner([]byte(strings.Repeat("k", 32)), 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestJWTSignAndVerify(t *testing.T) {
+	signer := testSigner(t)
+	now := time.Unix(1700000000, 0)
+
+	grants := Grants{Roles: []string{RoleSupport}, Permissions: []string{PermissionUsersRead}}
+	token, err := signer.Sign(42, "family", true, grants, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := claims.UserID()
+	if err != nil || userID != 42 || claims.SessionID != "family" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if !claims.Grants().HasRole(RoleSupport) || !claims.Grants().Can(PermissionUsersRead) || claims.Grants().Can(PermissionUsersWrite) {
+		t.Fatalf("unexpected grants %+v", claims.Grants())
+	}
+
+	if _, err := signer.Verify(token, now.Add(time.Hour)); err != ErrInvalidJWT {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestJWTRejectsTampering(t *testing.T) {
+	signer := testSigner(t)
+	now := time.Unix(1700000000, 0)
+
+	token, err := signer.Sign(42, "family", false, Grants{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"huddle","sub":"1","exp":9999999999}`))
+	if _, err := signer.Verify(parts[0]+"."+forged+"."+parts[2], now); err != ErrInvalidJWT {
+		t.Fatalf("expected modified payload to be rejected, got %v", err)
+	}
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	if _, err := signer.Verify(none+"."+parts[1]+".", now); err != ErrInvalidJWT {
+		t.Fatalf("expected alg none to be rejected, got %v", err)
+	}
+
+	other, _ := NewJWTSigner([]byte(strings.Repeat("x", 32)), 15*time.Minute)
+	if _, err := other.Verify(token, now); err != ErrInvalidJWT {
+		t.Fatalf("expected token signed with another key to be rejected, got %v", err)
+	}
+}
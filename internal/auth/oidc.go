@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"huddle-backend/internal/config"
+
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/providers/openidConnect"
+)
+
+var oidcProviderName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// reservedOIDCProviderNames cannot name an OIDC provider: the built-in
+// providers, the static /auth/* routes that /auth/<name> would clash with,
+// and the providers recorded on password and impersonation logins.
+var reservedOIDCProviderNames = []string{
+	"google", "github",
+	"csrf", "register", "login", "email", "token", "logout",
+	PasswordProvider, ImpersonationProvider,
+}
+
+// OIDCProviderConfig describes an OpenID Connect provider such as Keycloak or
+// Dex. The claim lists name, in order of preference, the claims that fill the
+// matching users columns.
+type OIDCProviderConfig struct {
+	Name         string
+	DiscoveryURL string
+	ClientID     string
+	ClientSecret string
+	CallbackURL  string
+	Scopes       []string
+
+	EmailClaims     []string
+	NameClaims      []string
+	NickNameClaims  []string
+	AvatarURLClaims []string
+}
+
+// LoadOIDCProviders reads the providers named in OIDC_PROVIDERS. Each one is
+// configured by OIDC_<NAME>_* variables, with dashes in the name written as
+// underscores.
+func LoadOIDCProviders() ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+
+	for _, name := range config.List("OIDC_PROVIDERS", nil) {
+		if !oidcProviderName.MatchString(name) {
+			return nil, fmt.Errorf("OIDC provider %q: names are lowercase letters, digits and dashes", name)
+		}
+		if slices.Contains(reservedOIDCProviderNames, name) || slices.ContainsFunc(providers, func(p OIDCProviderConfig) bool {
+			return p.Name == name
+		}) {
+			return nil, fmt.Errorf("OIDC provider %q: name already in use", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DiscoveryURL: os.Getenv(prefix + "DISCOVERY_URL"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			CallbackURL:  os.Getenv(prefix + "CALLBACK_URL"),
+			Scopes:       config.List(prefix+"SCOPES", []string{"openid", "profile", "email"}),
+
+			EmailClaims:     config.List(prefix+"CLAIM_EMAIL", []string{openidConnect.EmailClaim}),
+			NameClaims:      config.List(prefix+"CLAIM_NAME", []string{openidConnect.NameClaim}),
+			NickNameClaims:  config.List(prefix+"CLAIM_NICK_NAME", []string{openidConnect.PreferredUsernameClaim, openidConnect.NicknameClaim}),
+			AvatarURLClaims: config.List(prefix+"CLAIM_AVATAR_URL", []string{openidConnect.PictureClaim}),
+		}
+
+		for _, variable := range []string{"DISCOVERY_URL", "CLIENT_ID", "CALLBACK_URL"} {
+			if os.Getenv(prefix+variable) == "" {
+				return nil, fmt.Errorf("OIDC provider %q: %s%s must be set", name, prefix, variable)
+			}
+		}
+
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// NewOIDCProvider fetches the discovery document and builds a goth provider
+// registered under cfg.Name, so it logs in at /auth/<name>.
+func NewOIDCProvider(cfg OIDCProviderConfig) (goth.Provider, error) {
+	provider, err := openidConnect.New(cfg.ClientID, cfg.ClientSecret, cfg.CallbackURL, cfg.DiscoveryURL, cfg.Scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading OIDC discovery document for %s: %w", cfg.Name, err)
+	}
+
+	provider.SetName(cfg.Name)
+	provider.EmailClaims = cfg.EmailClaims
+	provider.NameClaims = cfg.NameClaims
+	provider.NickNameClaims = cfg.NickNameClaims
+	provider.AvatarURLClaims = cfg.AvatarURLClaims
+	return provider, nil
+}
+
+// oidcProviders builds every configured provider. A provider whose discovery
+// document cannot be fetched is skipped so that an identity provider outage
+// does not keep the API from starting.
+func oidcProviders() []goth.Provider {
+	configs, err := LoadOIDCProviders()
+	if err != nil {
+		log.Fatalf("OIDC providers: %v", err)
+	}
+
+	var providers []goth.Provider
+	for _, cfg := range configs {
+		provider, err := NewOIDCProvider(cfg)
+		if err != nil {
+			log.Printf("OIDC provider %s disabled: %v", cfg.Name, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/markbates/goth"
+)
+
+// mockIssuer is a minimal OpenID Connect provider: discovery, a token
+// endpoint that accepts one code, and a userinfo endpoint.
+func mockIssuer(t *testing.T, claims map[string]any) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "test-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		idClaims := map[string]any{
+			"iss": server.URL,
+			"aud": "huddle",
+			"sub": claims["sub"],
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		payload, _ := json.Marshal(idClaims)
+		idToken := "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(claims)
+	})
+
+	return server
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "keycloak, corp-dex")
+	t.Setenv("OIDC_KEYCLOAK_DISCOVERY_URL", "https://sso.example/.well-known/openid-configuration")
+	t.Setenv("OIDC_KEYCLOAK_CLIENT_ID", "huddle")
+	t.Setenv("OIDC_KEYCLOAK_CALLBACK_URL", "https://api.example/auth/keycloak/callback")
+	t.Setenv("OIDC_KEYCLOAK_CLAIM_AVATAR_URL", "avatar, picture")
+	t.Setenv("OIDC_CORP_DEX_DISCOVERY_URL", "https://dex.example/.well-known/openid-configuration")
+	t.Setenv("OIDC_CORP_DEX_CLIENT_ID", "huddle")
+
+	if _, err := LoadOIDCProviders(); err == nil {
+		t.Fatal("expected missing callback URL to be rejected")
+	}
+
+	t.Setenv("OIDC_CORP_DEX_CALLBACK_URL", "https://api.example/auth/corp-dex/callback")
+	providers, err := LoadOIDCProviders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].Name != "keycloak" || providers[1].Name != "corp-dex" {
+		t.Fatalf("unexpected providers %+v", providers)
+	}
+	if got := providers[0].AvatarURLClaims; len(got) != 2 || got[0] != "avatar" {
+		t.Fatalf("unexpected avatar claims %v", got)
+	}
+
+	for _, name := range []string{"github", "login", "token", "logout", "password", "impersonation"} {
+		t.Setenv("OIDC_PROVIDERS", name)
+		if _, err := LoadOIDCProviders(); err == nil {
+			t.Errorf("expected reserved provider name %q to be rejected", name)
+		}
+	}
+}
+
+func TestOIDCProviderLogin(t *testing.T) {
+	issuer := mockIssuer(t, map[string]any{
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"display_name":   "Ada Lovelace",
+		"username":       "ada",
+		"avatar":         "https://cdn.example/ada.png",
+	})
+
+	provider, err := NewOIDCProvider(OIDCProviderConfig{
+		Name:            "keycloak",
+		DiscoveryURL:    issuer.URL + "/.well-known/openid-configuration",
+		ClientID:        "huddle",
+		ClientSecret:    "secret",
+		CallbackURL:     "https://api.example/auth/keycloak/callback",
+		Scopes:          []string{"openid", "email"},
+		EmailClaims:     []string{"email"},
+		NameClaims:      []string{"display_name"},
+		NickNameClaims:  []string{"username"},
+		AvatarURLClaims: []string{"avatar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Name() != "keycloak" {
+		t.Fatalf("provider name = %q", provider.Name())
+	}
+
+	session, err := provider.BeginAuth("state-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := session.GetAuthURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+	if parsed.Path != "/authorize" || parsed.Query().Get("state") != "state-123" || parsed.Query().Get("client_id") != "huddle" {
+		t.Fatalf("unexpected auth URL %s", authURL)
+	}
+
+	if _, err := session.Authorize(provider, url.Values{"code": {"test-code"}}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := provider.FetchUser(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := goth.User{
+		Provider:  "keycloak",
+		UserID:    "user-1",
+		Email:     "ada@example.com",
+		Name:      "Ada Lovelace",
+		NickName:  "ada",
+		AvatarURL: "https://cdn.example/ada.png",
+	}
+	if user.Provider != want.Provider || user.UserID != want.UserID || user.Email != want.Email ||
+		user.Name != want.Name || user.NickName != want.NickName || user.AvatarURL != want.AvatarURL {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if !providerVerifiedEmail(user) {
+		t.Fatal("expected email_verified claim to mark the email verified")
+	}
+}
+
+func TestOIDCProviderRejectsBadCode(t *testing.T) {
+	issuer := mockIssuer(t, map[string]any{"sub": "user-1"})
+
+	provider, err := NewOIDCProvider(OIDCProviderConfig{
+		Name:         "dex",
+		DiscoveryURL: issuer.URL + "/.well-known/openid-configuration",
+		ClientID:     "huddle",
+		CallbackURL:  "https://api.example/auth/dex/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, _ := provider.BeginAuth("state")
+	if _, err := session.Authorize(provider, url.Values{"code": {"stolen"}}); err == nil {
+		t.Fatal("expected unknown code to be rejected")
+	}
+}
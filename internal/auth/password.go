@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	MinPasswordLength = 10
+	MaxPasswordLength = 128
+
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+var commonPasswords = map[string]bool{
+	"password123":   true,
+	"password1234":  true,
+	"1234567890":    true,
+	"qwertyuiop":    true,
+	"qwerty12345":   true,
+	"iloveyou123":   true,
+	"letmein1234":   true,
+	"welcome1234":   true,
+	"huddle12345":   true,
+	"administrator": true,
+}
+
+// ValidatePassword checks a new password against the strength rules. The
+// user's email and username are rejected as part of the password.
+func ValidatePassword(password, email, username string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must not exceed %d characters", MaxPasswordLength)
+	}
+
+	var hasLetter, hasOther bool
+	for _, char := range password {
+		if unicode.IsLetter(char) {
+			hasLetter = true
+		} else {
+			hasOther = true
+		}
+	}
+	if !hasLetter || !hasOther {
+		return fmt.Errorf("password must contain letters and at least one number or symbol")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return fmt.Errorf("password is too common")
+	}
+
+	localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(localPart) >= 3 && strings.Contains(lower, localPart) {
+		return fmt.Errorf("password must not contain your email address")
+	}
+	if len(username) >= 3 && strings.Contains(lower, strings.ToLower(username)) {
+		return fmt.Errorf("password must not contain your username")
+	}
+
+	return nil
+}
+
+// HashPassword derives an argon2id hash encoded in the PHC string format.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// VerifyPassword compares password with an encoded argon2id hash in constant
+// time, using the parameters stored in the hash.
+func VerifyPassword(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errMalformedHash
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errMalformedHash
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errMalformedHash
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// burnPasswordCheck performs a hash comparison that always fails, so that
+// logins for unknown accounts take as long as logins with a wrong password.
+func burnPasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("huddle-dummy-password")
+	})
+	VerifyPassword(password, dummyHash)
+}
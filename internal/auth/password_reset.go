@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrWeakPassword      = errors.New("password does not meet the requirements")
+)
+
+// newToken returns a random URL-safe token and the hash stored in its place.
+// Only the hash is persisted, so a leaked database cannot be used to redeem
+// outstanding tokens.
+func newToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset emails a single-use reset link to the account
+// registered with email. Unknown emails are silently ignored so callers
+// cannot learn which addresses have an account.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return fmt.Errorf("error generating reset token: %w", err)
+	}
+
+	ttl := config.Duration("PASSWORD_RESET_TTL", time.Hour)
+	if _, err := s.queries.CreatePasswordResetToken(ctx, sqlc.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return fmt.Errorf("error creating reset token: %w", err)
+	}
+
+	return s.mail.Send(ctx, user.Email, "password_reset", map[string]string{
+		"Username":  user.Username,
+		"ResetURL":  os.Getenv("FRONTEND_URL") + "/reset-password?token=" + url.QueryEscape(token),
+		"ExpiresIn": ttl.String(),
+	})
+}
+
+// ResetPassword redeems a reset token and sets a new password. The token is
+// consumed atomically, the email is marked verified, every other outstanding
+// token of the user is dropped, and all sessions and personal access tokens
+// are revoked. Passwords that fail ValidatePassword return an error wrapping
+// ErrWeakPassword and leave the token usable.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	resetToken, err := s.queries.GetValidPasswordResetToken(ctx, hashToken(token))
+	if err == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return fmt.Errorf("error getting reset token: %w", err)
+	}
+
+	user, err := s.queries.GetUserByID(ctx, resetToken.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	if err := ValidatePassword(password, user.Email, user.Username); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		consumed, err := q.ConsumePasswordResetToken(ctx, resetToken.ID)
+		if err != nil {
+			return fmt.Errorf("error consuming reset token: %w", err)
+		}
+		if consumed == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := q.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: sql.NullString{String: hash, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("error updating password: %w", err)
+		}
+		// The reset link reached the user's inbox, which proves they own it.
+		if _, err := q.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{
+			ID:    user.ID,
+			Email: user.Email,
+		}); err != nil {
+			return fmt.Errorf("error verifying email: %w", err)
+		}
+		if err := q.DeleteUserPasswordResetTokens(ctx, user.ID); err != nil {
+			return fmt.Errorf("error deleting reset tokens: %w", err)
+		}
+		if err := q.DeleteUserSessions(ctx, user.ID); err != nil {
+			return fmt.Errorf("error deleting sessions: %w", err)
+		}
+		if err := q.DeleteUserPersonalAccessTokens(ctx, user.ID); err != nil {
+			return fmt.Errorf("error deleting access tokens: %w", err)
+		}
+		return nil
+	})
+}
+
+// PurgeExpiredPasswordResets deletes used and expired reset tokens and
+// returns how many were removed.
+func (s *Service) PurgeExpiredPasswordResets(ctx context.Context) (int64, error) {
+	deleted, err := s.queries.DeleteExpiredPasswordResetTokens(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired reset tokens: %w", err)
+	}
+	return deleted, nil
+}
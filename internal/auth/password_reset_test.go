@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+var resetURLPattern = regexp.MustCompile(`/reset-password\?token=(\S+)`)
+
+func TestResetPasswordRevokesAccess(t *testing.T) {
+	s, mailer := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	if _, err := s.CreateSession(ctx, user, "password", "192.0.2.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	accessToken, _, err := s.CreateAccessToken(ctx, user.ID, "script", []string{ScopeAccountRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := createTestUser(t, s, "grace")
+	otherToken, _, err := s.CreateAccessToken(ctx, other.ID, "script", []string{ScopeAccountRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RequestPasswordReset(ctx, user.Email); err != nil {
+		t.Fatal(err)
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected a reset email, got %d", len(messages))
+	}
+	match := resetURLPattern.FindStringSubmatch(messages[0].Text)
+	if match == nil {
+		t.Fatalf("no reset link in %q", messages[0].Text)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ResetPassword(ctx, token, "a much better passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.ListUserSessions(ctx, user.ID)
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected every session to be ended, got %d: %v", len(sessions), err)
+	}
+	if _, err := s.AuthenticateAccessToken(ctx, accessToken); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("expected the access token to be revoked, got %v", err)
+	}
+	if _, err := s.AuthenticateAccessToken(ctx, otherToken); err != nil {
+		t.Fatalf("expected another user's access token to keep working, got %v", err)
+	}
+
+	if err := s.ResetPassword(ctx, token, "another fine passphrase"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected the token to work once, got %v", err)
+	}
+}
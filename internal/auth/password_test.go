@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashAndVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery 9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") {
+		t.Fatalf("unexpected hash format: %s", hash)
+	}
+
+	ok, err := VerifyPassword("correct horse battery 9", hash)
+	if err != nil || !ok {
+		t.Fatalf("expected password to verify, got %v, %v", ok, err)
+	}
+
+	ok, err = VerifyPassword("wrong horse battery 9", hash)
+	if err != nil || ok {
+		t.Fatalf("expected wrong password to fail, got %v, %v", ok, err)
+	}
+
+	if _, err := VerifyPassword("anything", "not-a-hash"); err == nil {
+		t.Fatal("expected malformed hash to return an error")
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"short1", false},
+		{"onlyletterslong", false},
+		{"12345678901", false},
+		{"password123", false},
+		{"jane.doe-secret1", false},
+		{"janedoe_99_secret", false},
+		{"tangerine-lamp-42", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePassword(tt.password, "jane.doe@example.com", "janedoe")
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidatePassword(%q) = %v, want valid %v", tt.password, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"huddle-backend/internal/config"
+)
+
+// AllowedOrigins lists the frontend origins from ALLOWED_ORIGINS. They may
+// make credentialed cross-origin requests and be used as return_to targets.
+func AllowedOrigins() []string {
+	return config.List("ALLOWED_ORIGINS", []string{"http://localhost:5173"})
+}
+
+// RedirectPolicy decides where a login may send the browser afterwards.
+// Relative targets resolve against FrontendURL; absolute ones must use one of
+// Origins. Either way the path must fall under one of Paths.
+type RedirectPolicy struct {
+	FrontendURL string
+	Origins     []string
+	Paths       []string
+}
+
+func LoadRedirectPolicy() RedirectPolicy {
+	return RedirectPolicy{
+		FrontendURL: strings.TrimSuffix(os.Getenv("FRONTEND_URL"), "/"),
+		Origins:     AllowedOrigins(),
+		Paths:       config.List("RETURN_TO_PATHS", []string{"/"}),
+	}
+}
+
+// Home is where logins land without a return_to.
+func (p RedirectPolicy) Home() string {
+	return p.FrontendURL + "/"
+}
+
+// Target validates returnTo and returns the absolute URL to redirect to.
+func (p RedirectPolicy) Target(returnTo string) (string, bool) {
+	if returnTo == "" || strings.ContainsFunc(returnTo, func(r rune) bool {
+		// Browsers treat backslashes as slashes and drop tabs and newlines,
+		// which turns "/\evil.example" into a protocol-relative URL.
+		return r == '\\' || r < 0x20 || r == 0x7f
+	}) {
+		return "", false
+	}
+
+	u, err := url.Parse(returnTo)
+	if err != nil || u.Opaque != "" || u.User != nil {
+		return "", false
+	}
+
+	var base string
+	if u.Scheme == "" && u.Host == "" {
+		if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+			return "", false
+		}
+		base = p.FrontendURL
+	} else {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "", false
+		}
+		base = u.Scheme + "://" + u.Host
+		if !slices.Contains(p.Origins, base) {
+			return "", false
+		}
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	if !p.pathAllowed(path) {
+		return "", false
+	}
+
+	target := base + u.EscapedPath()
+	if u.Path == "" {
+		target += "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		target += "#" + u.EscapedFragment()
+	}
+	return target, true
+}
+
+func (p RedirectPolicy) pathAllowed(path string) bool {
+	// Dot segments would be resolved by the browser after the prefix check.
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	for _, prefix := range p.Paths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginState travels through the OAuth state parameter. gothic compares the
+// parameter with the copy it stored in its own cookie, so the callback can
+// trust it. The nonce keeps the state unguessable.
+type LoginState struct {
+	Nonce    string `json:"n"`
+	ReturnTo string `json:"r,omitempty"`
+	JSON     bool   `json:"j,omitempty"`
+}
+
+// NewLoginState encodes a fresh state for a login that should end at
+// returnTo, or answer with JSON instead of a redirect when jsonMode is set.
+func NewLoginState(returnTo string, jsonMode bool) (string, error) {
+	nonce, _, err := newToken()
+	if err != nil {
+		return "", fmt.Errorf("error generating state: %w", err)
+	}
+
+	b, err := json.Marshal(LoginState{Nonce: nonce, ReturnTo: returnTo, JSON: jsonMode})
+	if err != nil {
+		return "", fmt.Errorf("error encoding state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ParseLoginState decodes a state from NewLoginState. Anything else yields the
+// zero LoginState, which means a plain redirect home.
+func ParseLoginState(state string) LoginState {
+	b, err := base64.RawURLEncoding.DecodeString(state)
+	if err != nil {
+		return LoginState{}
+	}
+
+	var loginState LoginState
+	if err := json.Unmarshal(b, &loginState); err != nil {
+		return LoginState{}
+	}
+	return loginState
+}
@@ -0,0 +1,64 @@
+package auth
+
+import "testing"
+
+func TestRedirectPolicyTarget(t *testing.T) {
+	policy := RedirectPolicy{
+		FrontendURL: "https://app.example",
+		Origins:     []string{"https://app.example", "https://admin.example"},
+		Paths:       []string{"/huddles", "/settings/"},
+	}
+
+	tests := []struct {
+		returnTo string
+		want     string
+	}{
+		{"/huddles", "https://app.example/huddles"},
+		{"/huddles/abc?tab=chat#latest", "https://app.example/huddles/abc?tab=chat#latest"},
+		{"/settings/profile", "https://app.example/settings/profile"},
+		{"https://admin.example/huddles/abc", "https://admin.example/huddles/abc"},
+		{"/huddlesx", ""},
+		{"/huddles/../admin", ""},
+		{"/huddles/%2e%2e/admin", ""},
+		{"/", ""},
+		{"huddles/abc", ""},
+		{"//evil.example/huddles", ""},
+		{"/\\evil.example/huddles", ""},
+		{"/huddles\t/abc", ""},
+		{"https://evil.example/huddles", ""},
+		{"https://app.example@evil.example/huddles", ""},
+		{"javascript:alert(1)", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := policy.Target(tt.returnTo)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("Target(%q) = %q, %v, want %q", tt.returnTo, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRedirectPolicyRootPath(t *testing.T) {
+	policy := RedirectPolicy{FrontendURL: "https://app.example", Paths: []string{"/"}}
+
+	if got, ok := policy.Target("/anything/at/all"); !ok || got != "https://app.example/anything/at/all" {
+		t.Fatalf("Target = %q, %v", got, ok)
+	}
+}
+
+func TestLoginState(t *testing.T) {
+	state, err := NewLoginState("/huddles/abc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := ParseLoginState(state)
+	if got.Nonce == "" || got.ReturnTo != "/huddles/abc" || !got.JSON {
+		t.Fatalf("unexpected state %+v", got)
+	}
+
+	if got := ParseLoginState("random-gothic-nonce"); got != (LoginState{}) {
+		t.Fatalf("expected zero state for foreign value, got %+v", got)
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// Roles seeded by the migrations.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+// Permissions seeded by the migrations. Roles are granted to users; routes
+// check permissions.
+const (
+	PermissionAdminAccess = "admin:access"
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionAuditRead   = "audit:read"
+	PermissionRolesManage = "roles:manage"
+	// PermissionUsersImpersonate allows logging in as another user.
+	PermissionUsersImpersonate = "users:impersonate"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrAdminExists  = errors.New("an admin already exists")
+)
+
+// Grants are the roles of a user and the permissions those roles carry.
+type Grants struct {
+	Roles       []string
+	Permissions []string
+}
+
+// SessionGrants returns the grants cached on a sessions row.
+func SessionGrants(roles, permissions string) Grants {
+	return Grants{
+		Roles:       strings.Fields(roles),
+		Permissions: strings.Fields(permissions),
+	}
+}
+
+func (g Grants) HasRole(role string) bool {
+	return slices.Contains(g.Roles, role)
+}
+
+func (g Grants) Can(permission string) bool {
+	return slices.Contains(g.Permissions, permission)
+}
+
+// RoleWithPermissions is a role as listed by the admin API.
+type RoleWithPermissions struct {
+	sqlc.Role
+	Permissions []string `json:"permissions"`
+}
+
+// UserGrants loads the current roles and permissions of a user.
+func (s *Service) UserGrants(ctx context.Context, userID int32) (Grants, error) {
+	return loadGrants(ctx, s.queries, userID)
+}
+
+func loadGrants(ctx context.Context, q *sqlc.Queries, userID int32) (Grants, error) {
+	roles, err := q.GetUserRoleNames(ctx, userID)
+	if err != nil {
+		return Grants{}, fmt.Errorf("error getting roles: %w", err)
+	}
+	permissions, err := q.GetUserPermissionNames(ctx, userID)
+	if err != nil {
+		return Grants{}, fmt.Errorf("error getting permissions: %w", err)
+	}
+	return Grants{Roles: roles, Permissions: permissions}, nil
+}
+
+func (s *Service) ListRoles(ctx context.Context) ([]RoleWithPermissions, error) {
+	roles, err := s.queries.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing roles: %w", err)
+	}
+	rolePermissions, err := s.queries.ListRolePermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing role permissions: %w", err)
+	}
+
+	result := make([]RoleWithPermissions, len(roles))
+	for i, role := range roles {
+		result[i] = RoleWithPermissions{Role: role, Permissions: []string{}}
+		for _, rp := range rolePermissions {
+			if rp.RoleID == role.ID {
+				result[i].Permissions = append(result[i].Permissions, rp.Name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// GrantRole gives a user a role. grantedBy is the admin granting it, or 0
+// when granted from the command line. The user's sessions pick up the new
+// grants immediately; JWT access tokens on their next refresh.
+func (s *Service) GrantRole(ctx context.Context, userID int32, role string, grantedBy int32) error {
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		if _, err := q.GetUserByID(ctx, userID); err == sql.ErrNoRows {
+			return ErrUserNotFound
+		} else if err != nil {
+			return fmt.Errorf("error getting user: %w", err)
+		}
+		r, err := getRole(ctx, q, role)
+		if err != nil {
+			return err
+		}
+		_, err = q.GrantUserRole(ctx, sqlc.GrantUserRoleParams{
+			UserID:    userID,
+			RoleID:    r.ID,
+			GrantedBy: sql.NullInt32{Int32: grantedBy, Valid: grantedBy != 0},
+		})
+		if err != nil {
+			return fmt.Errorf("error granting role: %w", err)
+		}
+		return refreshSessionGrants(ctx, q, userID)
+	})
+}
+
+// RevokeRole takes a role away from a user and updates their sessions.
+func (s *Service) RevokeRole(ctx context.Context, userID int32, role string) error {
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		r, err := getRole(ctx, q, role)
+		if err != nil {
+			return err
+		}
+		if _, err := q.RevokeUserRole(ctx, sqlc.RevokeUserRoleParams{UserID: userID, RoleID: r.ID}); err != nil {
+			return fmt.Errorf("error revoking role: %w", err)
+		}
+		return refreshSessionGrants(ctx, q, userID)
+	})
+}
+
+// BootstrapAdmin makes the user with email the first admin. It refuses once
+// any admin exists, so that it cannot be used to escalate later on.
+func (s *Service) BootstrapAdmin(ctx context.Context, email string) (sqlc.User, error) {
+	user, err := s.queries.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err == sql.ErrNoRows {
+		return sqlc.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		// Locking the admin role makes concurrent bootstraps wait for each
+		// other, so the second one counts the admin the first one granted.
+		admin, err := q.LockRoleByName(ctx, RoleAdmin)
+		if err == sql.ErrNoRows {
+			return ErrRoleNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error locking role: %w", err)
+		}
+		count, err := q.CountRoleMembers(ctx, admin.ID)
+		if err != nil {
+			return fmt.Errorf("error counting admins: %w", err)
+		}
+		if count > 0 {
+			return ErrAdminExists
+		}
+		if _, err := q.GrantUserRole(ctx, sqlc.GrantUserRoleParams{UserID: user.ID, RoleID: admin.ID}); err != nil {
+			return fmt.Errorf("error granting role: %w", err)
+		}
+		return refreshSessionGrants(ctx, q, user.ID)
+	})
+	return user, err
+}
+
+func getRole(ctx context.Context, q *sqlc.Queries, name string) (sqlc.Role, error) {
+	role, err := q.GetRoleByName(ctx, name)
+	if err == sql.ErrNoRows {
+		return sqlc.Role{}, ErrRoleNotFound
+	}
+	if err != nil {
+		return sqlc.Role{}, fmt.Errorf("error getting role: %w", err)
+	}
+	return role, nil
+}
+
+// refreshSessionGrants rewrites the grants cached on every session of a user,
+// and ends the impersonations they run once they may no longer impersonate.
+func refreshSessionGrants(ctx context.Context, q *sqlc.Queries, userID int32) error {
+	grants, err := loadGrants(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+	if !grants.Can(PermissionUsersImpersonate) {
+		if err := q.DeleteImpersonatorSessions(ctx, sql.NullInt32{Int32: userID, Valid: true}); err != nil {
+			return fmt.Errorf("error ending impersonations: %w", err)
+		}
+	}
+	err = q.UpdateUserSessionGrants(ctx, sqlc.UpdateUserSessionGrantsParams{
+		UserID:      userID,
+		Roles:       strings.Join(grants.Roles, " "),
+		Permissions: strings.Join(grants.Permissions, " "),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating session grants: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestBootstrapAdminRace bootstraps two admins at once, the way two
+// operators running the command together would. Only one may win.
+func TestBootstrapAdminRace(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	users := []string{"ada", "grace"}
+	for _, username := range users {
+		createTestUser(t, s, username)
+	}
+
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i, username := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.BootstrapAdmin(ctx, username+"@example.com")
+		}()
+	}
+	wg.Wait()
+
+	var won, refused int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, ErrAdminExists):
+			refused++
+		default:
+			t.Fatal(err)
+		}
+	}
+	if won != 1 || refused != 1 {
+		t.Fatalf("expected one bootstrap to win and one to be refused, got %v", errs)
+	}
+
+	if _, err := s.BootstrapAdmin(ctx, "ada@example.com"); !errors.Is(err, ErrAdminExists) {
+		t.Fatalf("expected ErrAdminExists once an admin exists, got %v", err)
+	}
+	if _, err := s.BootstrapAdmin(ctx, "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
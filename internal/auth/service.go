@@ -3,23 +3,49 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/encryption"
+	"huddle-backend/internal/mail"
 
 	"github.com/markbates/goth"
 )
 
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrEmailInUse       = errors.New("an account with this email already exists, sign in with your original method and connect this provider from your settings")
+	ErrIdentityInUse    = errors.New("this login is already connected to another account")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastLoginMethod  = errors.New("cannot remove the last login method of an account")
+)
+
 type Service struct {
+	db      *sql.DB
 	queries *sqlc.Queries
+	keyring *encryption.Keyring
+	mail    *mail.Service
+	jwt     *JWTSigner
+	policy  SessionPolicy
 }
 
-func NewService(queries *sqlc.Queries) *Service {
-	return &Service{queries: queries}
+func NewService(db *sql.DB, keyring *encryption.Keyring, mailService *mail.Service, jwtSigner *JWTSigner) *Service {
+	return &Service{
+		db:      db,
+		queries: sqlc.New(db),
+		keyring: keyring,
+		mail:    mailService,
+		jwt:     jwtSigner,
+		policy:  LoadSessionPolicy(),
+	}
 }
 
 func GenerateSessionID() (string, error) {
@@ -30,23 +56,64 @@ func GenerateSessionID() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
+// SessionHandle derives the public identifier for a session. The session ID
+// itself is the cookie credential, so it is never sent back to clients.
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
 func (s *Service) FindOrCreateOAuthUser(ctx context.Context, gothUser goth.User) (sqlc.User, error) {
 	log.Printf("=== FindOrCreateOAuthUser ===")
 	log.Printf("Provider: %s, UserID: %s, Email: %s", gothUser.Provider, gothUser.UserID, gothUser.Email)
 
-	user, err := s.queries.GetUserByProviderID(ctx, sqlc.GetUserByProviderIDParams{
-		Provider:       sql.NullString{String: gothUser.Provider, Valid: true},
-		ProviderUserID: sql.NullString{String: gothUser.UserID, Valid: true},
+	// Emails are stored lower-cased, so a provider that keeps the case the
+	// user typed must not miss or duplicate the account.
+	gothUser.Email = strings.ToLower(strings.TrimSpace(gothUser.Email))
+
+	identity, err := s.queries.GetUserIdentityByProvider(ctx, sqlc.GetUserIdentityByProviderParams{
+		Provider:       gothUser.Provider,
+		ProviderUserID: gothUser.UserID,
 	})
 
 	if err == nil {
-		log.Printf("User found, updating tokens")
-		return s.queries.UpdateUserOAuthTokens(ctx, sqlc.UpdateUserOAuthTokensParams{
-			ID:           user.ID,
-			AccessToken:  sql.NullString{String: gothUser.AccessToken, Valid: true},
-			RefreshToken: sql.NullString{String: gothUser.RefreshToken, Valid: true},
-			ExpiresAt:    sql.NullTime{Time: gothUser.ExpiresAt, Valid: !gothUser.ExpiresAt.IsZero()},
-		})
+		user, err := s.queries.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return sqlc.User{}, fmt.Errorf("error getting user: %w", err)
+		}
+		if err := CheckSuspension(user); err != nil {
+			return sqlc.User{}, err
+		}
+		log.Printf("Identity found, updating tokens")
+		if _, err := s.updateIdentityTokens(ctx, identity.ID, gothUser); err != nil {
+			return sqlc.User{}, err
+		}
+		return s.syncProviderVerifiedEmail(ctx, user, gothUser)
+	}
+
+	if err != sql.ErrNoRows {
+		log.Printf("Error checking existing identity: %v", err)
+		return sqlc.User{}, fmt.Errorf("error checking existing identity: %w", err)
+	}
+
+	existingUser, err := s.queries.GetUserByEmail(ctx, gothUser.Email)
+	if err == nil {
+		// Both sides must have proven ownership of the address, otherwise
+		// someone could pre-register a victim's email and wait for them to
+		// sign in with a provider.
+		if !providerVerifiedEmail(gothUser) || !existingUser.EmailVerifiedAt.Valid {
+			log.Printf("Email already used by user %d and not verified on both sides by %s", existingUser.ID, gothUser.Provider)
+			return sqlc.User{}, ErrEmailInUse
+		}
+		if err := CheckSuspension(existingUser); err != nil {
+			return sqlc.User{}, err
+		}
+
+		log.Printf("Linking %s identity to user %d by verified email", gothUser.Provider, existingUser.ID)
+		if _, err := s.createIdentity(ctx, s.queries, existingUser.ID, gothUser); err != nil {
+			return sqlc.User{}, err
+		}
+		return existingUser, nil
 	}
 
 	if err != sql.ErrNoRows {
@@ -56,53 +123,110 @@ func (s *Service) FindOrCreateOAuthUser(ctx context.Context, gothUser goth.User)
 
 	log.Printf("User not found, creating new user")
 
-	username := gothUser.NickName
-	if username == "" {
-		username = gothUser.Email
+	params := sqlc.CreateOAuthUserParams{
+		Email:           gothUser.Email,
+		AvatarUrl:       sql.NullString{String: gothUser.AvatarURL, Valid: gothUser.AvatarURL != ""},
+		Provider:        sql.NullString{String: gothUser.Provider, Valid: true},
+		ProviderUserID:  sql.NullString{String: gothUser.UserID, Valid: true},
+		Name:            sql.NullString{String: gothUser.Name, Valid: gothUser.Name != ""},
+		FirstName:       sql.NullString{String: gothUser.FirstName, Valid: gothUser.FirstName != ""},
+		LastName:        sql.NullString{String: gothUser.LastName, Valid: gothUser.LastName != ""},
+		NickName:        sql.NullString{String: gothUser.NickName, Valid: gothUser.NickName != ""},
+		Description:     sql.NullString{String: gothUser.Description, Valid: gothUser.Description != ""},
+		Location:        sql.NullString{String: gothUser.Location, Valid: gothUser.Location != ""},
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: providerVerifiedEmail(gothUser)},
 	}
 
-	params := sqlc.CreateOAuthUserParams{
-		Username:       username,
-		Email:          gothUser.Email,
-		AvatarUrl:      sql.NullString{String: gothUser.AvatarURL, Valid: gothUser.AvatarURL != ""},
-		Provider:       sql.NullString{String: gothUser.Provider, Valid: true},
-		ProviderUserID: sql.NullString{String: gothUser.UserID, Valid: true},
-		AccessToken:    sql.NullString{String: gothUser.AccessToken, Valid: true},
-		RefreshToken:   sql.NullString{String: gothUser.RefreshToken, Valid: true},
-		ExpiresAt:      sql.NullTime{Time: gothUser.ExpiresAt, Valid: !gothUser.ExpiresAt.IsZero()},
-		Name:           sql.NullString{String: gothUser.Name, Valid: gothUser.Name != ""},
-		FirstName:      sql.NullString{String: gothUser.FirstName, Valid: gothUser.FirstName != ""},
-		LastName:       sql.NullString{String: gothUser.LastName, Valid: gothUser.LastName != ""},
-		NickName:       sql.NullString{String: gothUser.NickName, Valid: gothUser.NickName != ""},
-		Description:    sql.NullString{String: gothUser.Description, Valid: gothUser.Description != ""},
-		Location:       sql.NullString{String: gothUser.Location, Valid: gothUser.Location != ""},
-	}
-
-	newUser, err := s.queries.CreateOAuthUser(ctx, params)
+	// Concurrent sign-ups can claim the same username between attempts, so
+	// collisions are detected on insert rather than checked up front.
+	base := usernameBase(gothUser)
+	var newUser sqlc.User
+	for attempt := 0; ; attempt++ {
+		params.Username, err = usernameCandidate(base, attempt)
+		if err != nil {
+			return sqlc.User{}, fmt.Errorf("error generating username: %w", err)
+		}
+
+		err = s.withTx(ctx, func(q *sqlc.Queries) error {
+			var err error
+			newUser, err = q.CreateOAuthUser(ctx, params)
+			if err != nil {
+				return fmt.Errorf("error creating user: %w", err)
+			}
+			_, err = s.createIdentity(ctx, q, newUser.ID, gothUser)
+			return err
+		})
+		if !isUsernameCollision(err) || attempt+1 == maxUsernameAttempts {
+			break
+		}
+		log.Printf("Username %q is taken, trying another", params.Username)
+	}
 	if err != nil {
 		log.Printf("Error creating user: %v", err)
-		return sqlc.User{}, fmt.Errorf("error creating user: %w", err)
+		return sqlc.User{}, err
 	}
 
-	log.Printf("User created successfully: %+v", newUser)
+	log.Printf("User created successfully: %d", newUser.ID)
 	return newUser, nil
 }
 
-func (s *Service) CreateSession(ctx context.Context, userID int32, provider, ipAddress, userAgent string) (sqlc.Session, error) {
+func (s *Service) withTx(ctx context.Context, fn func(q *sqlc.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateSession starts a session for user. Users with two-factor
+// authentication get a short-lived pending session that only becomes usable
+// after CompleteTwoFactor. Suspended users get a *SuspendedError.
+func (s *Service) CreateSession(ctx context.Context, user sqlc.User, provider, ipAddress, userAgent string) (sqlc.Session, error) {
+	if err := CheckSuspension(user); err != nil {
+		return sqlc.Session{}, err
+	}
+
 	sessionID, err := GenerateSessionID()
 	if err != nil {
 		return sqlc.Session{}, fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	mfaPending := user.TotpEnabledAt.Valid
+	expiresAt := time.Now().Add(s.policy.IdleTimeout)
+	if mfaPending {
+		expiresAt = time.Now().Add(s.policy.MFATimeout)
+	}
+
+	// The session caches the user's roles, so RequireAuth can authorize
+	// without another query. GrantRole and RevokeRole keep it current.
+	grants, err := s.UserGrants(ctx, user.ID)
+	if err != nil {
+		return sqlc.Session{}, err
+	}
+
+	// Logging in keeps an account that was scheduled for deletion. With
+	// two-factor on, that waits until the second factor is verified.
+	if !mfaPending {
+		if err := s.cancelAccountDeletion(ctx, user, ipAddress, userAgent); err != nil {
+			return sqlc.Session{}, err
+		}
+	}
 
 	return s.queries.CreateSession(ctx, sqlc.CreateSessionParams{
-		ID:        sessionID,
-		UserID:    userID,
-		Provider:  sql.NullString{String: provider, Valid: provider != ""},
-		IpAddress: sql.NullString{String: ipAddress, Valid: ipAddress != ""},
-		UserAgent: sql.NullString{String: userAgent, Valid: userAgent != ""},
-		ExpiresAt: expiresAt,
+		ID:          sessionID,
+		UserID:      user.ID,
+		Provider:    sql.NullString{String: provider, Valid: provider != ""},
+		IpAddress:   sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		UserAgent:   sql.NullString{String: userAgent, Valid: userAgent != ""},
+		ExpiresAt:   expiresAt,
+		MfaPending:  mfaPending,
+		Roles:       strings.Join(grants.Roles, " "),
+		Permissions: strings.Join(grants.Permissions, " "),
 	})
 }
 
@@ -110,14 +234,102 @@ func (s *Service) GetSessionByID(ctx context.Context, sessionID string) (sqlc.Ge
 	return s.queries.GetSessionByID(ctx, sessionID)
 }
 
+// RenewSession slides the expiry of a session forward once it is past the
+// renewal point of its lifetime. Sessions that were renewed recently are left
+// alone, so the sessions row is written at most once per renewal window. It
+// reports the session's expiry and whether it was extended.
+func (s *Service) RenewSession(ctx context.Context, session sqlc.GetSessionByIDRow) (time.Time, bool, error) {
+	// Impersonation is time-boxed.
+	if session.ImpersonatorID.Valid {
+		return session.ExpiresAt, false, nil
+	}
+
+	expiresAt, ok := s.policy.renewal(session.ExpiresAt, session.CreatedAt, time.Now())
+	if !ok {
+		return session.ExpiresAt, false, nil
+	}
+
+	if err := s.queries.ExtendSession(ctx, sqlc.ExtendSessionParams{
+		ID:        session.ID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return session.ExpiresAt, false, fmt.Errorf("error extending session: %w", err)
+	}
+
+	return expiresAt, true, nil
+}
+
 func (s *Service) DeleteSession(ctx context.Context, sessionID string) error {
 	return s.queries.DeleteSession(ctx, sessionID)
 }
 
+// ListUserSessions returns the logins a user can see and revoke. Sessions an
+// admin is impersonating them with and logins still waiting for a second
+// factor are left out.
+func (s *Service) ListUserSessions(ctx context.Context, userID int32) ([]sqlc.Session, error) {
+	sessions, err := s.queries.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeUserSession deletes the session identified by handle, provided it
+// belongs to userID. For refresh tokens the whole token family is revoked.
+func (s *Service) RevokeUserSession(ctx context.Context, userID int32, handle string) error {
+	sessions, err := s.ListUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if SessionHandle(SessionKey(session)) != handle {
+			continue
+		}
+
+		if session.TokenFamily.Valid {
+			_, err = s.queries.DeleteSessionFamily(ctx, session.TokenFamily)
+		} else {
+			err = s.queries.DeleteSession(ctx, session.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("error revoking session: %w", err)
+		}
+		return nil
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeOtherUserSessions logs userID out of every session except
+// currentSessionID, which is a cookie session ID or a refresh token family.
+func (s *Service) RevokeOtherUserSessions(ctx context.Context, userID int32, currentSessionID string) (int64, error) {
+	revoked, err := s.queries.DeleteOtherUserSessions(ctx, sqlc.DeleteOtherUserSessionsParams{
+		UserID: userID,
+		ID:     currentSessionID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// DeleteUserSessions ends every session and refresh token of a user, along
+// with any impersonation they are running.
 func (s *Service) DeleteUserSessions(ctx context.Context, userID int32) error {
 	return s.queries.DeleteUserSessions(ctx, userID)
 }
 
+// PurgeExpiredSessions deletes every session past its expiry and returns how
+// many were removed.
+func (s *Service) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	deleted, err := s.queries.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired sessions: %w", err)
+	}
+	return deleted, nil
+}
+
 func (s *Service) GetUserByID(ctx context.Context, id int32) (sqlc.User, error) {
 	return s.queries.GetUserByID(ctx, id)
 }
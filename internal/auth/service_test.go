@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/encryption"
+	"huddle-backend/internal/mail"
+)
+
+// newTestService returns a Service on a fresh database, and the mailer its
+// emails go to. The test is skipped when Docker is not available.
+func newTestService(t *testing.T) (*Service, *mail.MemoryMailer) {
+	t.Helper()
+	db, _ := dbtest.New(t)
+	mailer := mail.NewMemoryMailer()
+	mailService, err := mail.NewService(mailer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := encryption.NewKeyring("test", map[string][]byte{"test": []byte(strings.Repeat("e", 32))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db, keyring, mailService, testSigner(t)), mailer
+}
+
+func createTestUser(t *testing.T, s *Service, username string) sqlc.User {
+	t.Helper()
+	user, err := s.queries.CreatePasswordUser(context.Background(), sqlc.CreatePasswordUserParams{
+		Username: username,
+		Email:    username + "@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestSessionPolicyRenewal(t *testing.T) {
+	policy := SessionPolicy{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour, RenewAfter: 0.5}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := sql.NullTime{Time: now.Add(-2 * time.Hour), Valid: true}
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		createdAt sql.NullTime
+		want      time.Time
+		renewed   bool
+	}{
+		{
+			name:      "renewed recently",
+			expiresAt: now.Add(45 * time.Minute),
+			createdAt: created,
+			want:      now.Add(45 * time.Minute),
+		},
+		{
+			name:      "at the renewal point",
+			expiresAt: now.Add(30 * time.Minute),
+			createdAt: created,
+			want:      now.Add(time.Hour),
+			renewed:   true,
+		},
+		{
+			name:      "past the renewal point",
+			expiresAt: now.Add(10 * time.Minute),
+			createdAt: created,
+			want:      now.Add(time.Hour),
+			renewed:   true,
+		},
+		{
+			name:      "capped at the max lifetime",
+			expiresAt: now.Add(10 * time.Minute),
+			createdAt: sql.NullTime{Time: now.Add(-23*time.Hour - 30*time.Minute), Valid: true},
+			want:      now.Add(30 * time.Minute),
+			renewed:   true,
+		},
+		{
+			name:      "max lifetime reached",
+			expiresAt: now.Add(10 * time.Minute),
+			createdAt: sql.NullTime{Time: now.Add(-24*time.Hour + 10*time.Minute), Valid: true},
+			want:      now.Add(10 * time.Minute),
+		},
+		{
+			name:      "no creation time",
+			expiresAt: now.Add(10 * time.Minute),
+			want:      now.Add(time.Hour),
+			renewed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, renewed := policy.renewal(tt.expiresAt, tt.createdAt, now)
+			if !got.Equal(tt.want) || renewed != tt.renewed {
+				t.Fatalf("got %v, %v; want %v, %v", got, renewed, tt.want, tt.renewed)
+			}
+		})
+	}
+}
+
+// TestRenewSession checks that a session is written to only once it is past
+// its renewal point, and that impersonation sessions are never extended.
+func TestRenewSession(t *testing.T) {
+	s, _ := newTestService(t)
+	s.policy = SessionPolicy{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour, RenewAfter: 0.5, MFATimeout: 10 * time.Minute}
+	ctx := context.Background()
+	admin := createTestUser(t, s, "admin")
+	user := createTestUser(t, s, "ada")
+
+	created, err := s.CreateSession(ctx, user, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.GetSessionByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt, renewed, err := s.RenewSession(ctx, session)
+	if err != nil || renewed || !expiresAt.Equal(session.ExpiresAt) {
+		t.Fatalf("expected a fresh session to be left alone, got %v, %v: %v", expiresAt, renewed, err)
+	}
+	if again, err := s.GetSessionByID(ctx, created.ID); err != nil || !again.UpdatedAt.Time.Equal(session.UpdatedAt.Time) {
+		t.Fatalf("expected no write for a fresh session, got %+v: %v", again, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET expires_at = NOW() + INTERVAL '20 minutes' WHERE id = $1`, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	session, err = s.GetSessionByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt, renewed, err = s.RenewSession(ctx, session)
+	if err != nil || !renewed || time.Until(expiresAt) < 59*time.Minute {
+		t.Fatalf("expected the session to slide to an hour from now, got %v, %v: %v", expiresAt, renewed, err)
+	}
+	if again, err := s.GetSessionByID(ctx, created.ID); err != nil || !again.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected the new expiry to be stored, got %+v: %v", again, err)
+	}
+
+	impersonation, err := s.StartImpersonation(ctx, admin.ID, user.ID, "192.0.2.9", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET expires_at = NOW() + INTERVAL '1 minute' WHERE id = $1`, impersonation.ID); err != nil {
+		t.Fatal(err)
+	}
+	session, err = s.GetSessionByID(ctx, impersonation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiresAt, renewed, err := s.RenewSession(ctx, session); err != nil || renewed || !expiresAt.Equal(session.ExpiresAt) {
+		t.Fatalf("expected impersonation not to be renewed, got %v, %v: %v", expiresAt, renewed, err)
+	}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+var (
+	ErrAccountSuspended  = errors.New("this account has been suspended")
+	ErrNotSuspended      = errors.New("user is not suspended")
+	ErrInvalidSuspension = errors.New("a suspension needs a reason and an end in the future")
+)
+
+// SuspendedError is returned when a suspended user tries to log in or use
+// the API. It matches ErrAccountSuspended with errors.Is.
+type SuspendedError struct {
+	// Until is when the suspension ends, or nil for a permanent ban.
+	Until  *time.Time
+	Reason string
+}
+
+func (e *SuspendedError) Error() string {
+	if e.Until == nil {
+		return "this account has been suspended permanently"
+	}
+	return "this account has been suspended until " + e.Until.UTC().Format(time.RFC3339)
+}
+
+func (e *SuspendedError) Is(target error) bool {
+	return target == ErrAccountSuspended
+}
+
+// Suspended reports whether a suspension recorded on a users row is in
+// effect at now. Suspensions with an end lapse by themselves.
+func Suspended(suspendedAt, suspendedUntil sql.NullTime, now time.Time) bool {
+	return suspendedAt.Valid && (!suspendedUntil.Valid || suspendedUntil.Time.After(now))
+}
+
+// CheckSuspension returns a *SuspendedError if user is suspended, or nil.
+func CheckSuspension(user sqlc.User) error {
+	return suspensionError(user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason)
+}
+
+// UserSuspension looks up whether a user is suspended. It returns a
+// *SuspendedError if they are, or nil. It serves requests that carry no users
+// row, such as JWT access tokens.
+func (s *Service) UserSuspension(ctx context.Context, userID int32) error {
+	row, err := s.queries.GetUserSuspension(ctx, userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error getting suspension: %w", err)
+	}
+	return suspensionError(row.SuspendedAt, row.SuspendedUntil, row.SuspensionReason)
+}
+
+// CheckAccessTokenUser makes sure JWT access tokens may be issued to a user.
+// Access tokens are verified without a lookup, so suspended accounts and
+// accounts scheduled for deletion are refused here instead. It returns a
+// *SuspendedError, ErrDeletionScheduled or nil.
+func (s *Service) CheckAccessTokenUser(ctx context.Context, userID int32) error {
+	row, err := s.queries.GetUserSuspension(ctx, userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error getting suspension: %w", err)
+	}
+	if err := suspensionError(row.SuspendedAt, row.SuspendedUntil, row.SuspensionReason); err != nil {
+		return err
+	}
+	if row.DeletionScheduledAt.Valid {
+		return ErrDeletionScheduled
+	}
+	return nil
+}
+
+// SuspendUser suspends a user until the given time, or permanently when until
+// is nil, and ends all of their sessions. Suspending a suspended user
+// replaces the suspension.
+func (s *Service) SuspendUser(ctx context.Context, userID int32, until *time.Time, reason string) error {
+	if reason == "" || (until != nil && !until.After(time.Now())) {
+		return ErrInvalidSuspension
+	}
+
+	params := sqlc.SuspendUserParams{
+		ID:               userID,
+		SuspensionReason: sql.NullString{String: reason, Valid: true},
+	}
+	if until != nil {
+		params.SuspendedUntil = sql.NullTime{Time: until.UTC(), Valid: true}
+	}
+
+	suspended, err := s.queries.SuspendUser(ctx, params)
+	if err != nil {
+		return fmt.Errorf("error suspending user: %w", err)
+	}
+	if suspended == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := s.DeleteUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("error deleting sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) UnsuspendUser(ctx context.Context, userID int32) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	unsuspended, err := s.queries.UnsuspendUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error unsuspending user: %w", err)
+	}
+	if unsuspended == 0 {
+		return ErrNotSuspended
+	}
+	return nil
+}
+
+func suspensionError(suspendedAt, suspendedUntil sql.NullTime, reason sql.NullString) error {
+	if !Suspended(suspendedAt, suspendedUntil, time.Now()) {
+		return nil
+	}
+	err := &SuspendedError{Reason: reason.String}
+	if suspendedUntil.Valid {
+		err.Until = &suspendedUntil.Time
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+
+	"github.com/markbates/goth"
+)
+
+func TestSuspended(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	at := sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+
+	tests := []struct {
+		name  string
+		at    sql.NullTime
+		until sql.NullTime
+		want  bool
+	}{
+		{"not suspended", sql.NullTime{}, sql.NullTime{}, false},
+		{"permanent", at, sql.NullTime{}, true},
+		{"until later", at, sql.NullTime{Time: now.Add(time.Hour), Valid: true}, true},
+		{"lapsed", at, sql.NullTime{Time: now.Add(-time.Minute), Valid: true}, false},
+	}
+	for _, tt := range tests {
+		if got := Suspended(tt.at, tt.until, now); got != tt.want {
+			t.Errorf("%s: Suspended = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSuspension(t *testing.T) {
+	if err := CheckSuspension(sqlc.User{}); err != nil {
+		t.Fatalf("expected no error for an active user, got %v", err)
+	}
+
+	until := time.Now().Add(24 * time.Hour)
+	err := CheckSuspension(sqlc.User{
+		SuspendedAt:      sql.NullTime{Time: time.Now(), Valid: true},
+		SuspendedUntil:   sql.NullTime{Time: until, Valid: true},
+		SuspensionReason: sql.NullString{String: "spam", Valid: true},
+	})
+	if !errors.Is(err, ErrAccountSuspended) {
+		t.Fatalf("expected ErrAccountSuspended, got %v", err)
+	}
+	var suspension *SuspendedError
+	if !errors.As(err, &suspension) || suspension.Reason != "spam" || suspension.Until == nil || !suspension.Until.Equal(until) {
+		t.Fatalf("unexpected suspension %+v", suspension)
+	}
+}
+
+func TestSuspendUserEndsSessions(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+	other := createTestUser(t, s, "grace")
+
+	session, err := s.CreateSession(ctx, user, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSession, err := s.CreateSession(ctx, other, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SuspendUser(ctx, user.ID, nil, "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetSessionByID(ctx, session.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected the suspended user's session to be deleted, got %v", err)
+	}
+	if _, err := s.GetSessionByID(ctx, otherSession.ID); err != nil {
+		t.Fatalf("expected another user's session to be kept, got %v", err)
+	}
+
+	if err := s.SuspendUser(ctx, user.ID+other.ID, nil, "spam"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := s.SuspendUser(ctx, other.ID, &past, "spam"); !errors.Is(err, ErrInvalidSuspension) {
+		t.Fatalf("expected ErrInvalidSuspension, got %v", err)
+	}
+}
+
+func TestSuspendedProfilesHidden(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	for _, username := range []string{"ada", "adam"} {
+		user := createTestUser(t, s, username)
+		if _, err := s.queries.CreateProfile(ctx, sqlc.CreateProfileParams{UserID: user.ID, Username: username}); err != nil {
+			t.Fatal(err)
+		}
+		if username == "adam" {
+			if err := s.SuspendUser(ctx, user.ID, nil, "spam"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	listed, err := s.queries.ListProfiles(ctx, sqlc.ListProfilesParams{Limit: 10})
+	if err != nil || len(listed) != 1 || listed[0].Username != "ada" {
+		t.Fatalf("expected only ada to be listed, got %+v: %v", listed, err)
+	}
+	found, err := s.queries.SearchProfilesByUsername(ctx, sqlc.SearchProfilesByUsernameParams{Username: "%ada%", Limit: 10})
+	if err != nil || len(found) != 1 || found[0].Username != "ada" {
+		t.Fatalf("expected only ada to be found, got %+v: %v", found, err)
+	}
+}
+
+// TestFindOrCreateOAuthUserRefusesSuspended covers both ways a provider login
+// reaches an account: through a linked identity and through a verified email.
+func TestFindOrCreateOAuthUserRefusesSuspended(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	linked := createTestUser(t, s, "ada")
+	if _, err := s.queries.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:         linked.ID,
+		Provider:       "github",
+		ProviderUserID: "ada-1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	byEmail := createTestUser(t, s, "grace")
+	if _, err := s.queries.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{ID: byEmail.ID, Email: byEmail.Email}); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []sqlc.User{linked, byEmail} {
+		if err := s.SuspendUser(ctx, user.ID, nil, "spam"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logins := []goth.User{
+		{Provider: "github", UserID: "ada-1", Email: linked.Email},
+		{Provider: "keycloak", UserID: "grace-1", Email: byEmail.Email, RawData: map[string]any{"email_verified": true}},
+	}
+	for _, login := range logins {
+		if _, err := s.FindOrCreateOAuthUser(ctx, login); !errors.Is(err, ErrAccountSuspended) {
+			t.Fatalf("%s: expected ErrAccountSuspended, got %v", login.UserID, err)
+		}
+	}
+
+	identities, err := s.ListIdentities(ctx, byEmail.ID)
+	if err != nil || len(identities) != 0 {
+		t.Fatalf("expected no identity to be linked to a suspended user, got %d: %v", len(identities), err)
+	}
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+const (
+	// RefreshTokenPrefix marks refresh tokens issued by the token endpoint.
+	RefreshTokenPrefix = "hdl_rt_"
+
+	// sessionKindRefresh marks sessions rows that hold a refresh token rather
+	// than a cookie session.
+	sessionKindRefresh = "refresh"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, every token of this login has been revoked")
+	ErrTwoFactorRequired   = errors.New("two-factor authentication required")
+)
+
+// TokenPair is the response of the token endpoints.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionKey identifies a session in listings. Refresh tokens get a new row on
+// every rotation, so they are identified by their family instead.
+func SessionKey(session sqlc.Session) string {
+	if session.TokenFamily.Valid {
+		return session.TokenFamily.String
+	}
+	return session.ID
+}
+
+// ExchangeSession trades a cookie session for an access and refresh token
+// pair. The cookie session is ended, so the login continues as a token
+// family. Suspended users and accounts scheduled for deletion get no tokens.
+func (s *Service) ExchangeSession(ctx context.Context, sessionID, ipAddress, userAgent string) (TokenPair, error) {
+	session, err := s.queries.GetSessionByID(ctx, sessionID)
+	if err == sql.ErrNoRows {
+		return TokenPair{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error getting session: %w", err)
+	}
+	if session.MfaPending {
+		return TokenPair{}, ErrTwoFactorRequired
+	}
+	// Tokens would outlive the time box of an impersonation.
+	if session.ImpersonatorID.Valid {
+		return TokenPair{}, ErrImpersonating
+	}
+	if err := s.CheckAccessTokenUser(ctx, session.UserID); err != nil {
+		return TokenPair{}, err
+	}
+
+	family, err := GenerateSessionID()
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error generating token family: %w", err)
+	}
+
+	var pair TokenPair
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		refresh := sqlc.CreateRefreshSessionParams{
+			UserID:      session.UserID,
+			Provider:    session.Provider,
+			IpAddress:   sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+			UserAgent:   sql.NullString{String: userAgent, Valid: userAgent != ""},
+			TokenFamily: sql.NullString{String: family, Valid: true},
+			CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		}
+		var err error
+		pair, err = s.issueTokenPair(ctx, q, refresh, session.EmailVerifiedAt.Valid)
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteSession(ctx, session.ID); err != nil {
+			return fmt.Errorf("error ending cookie session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	s.RecordAuthEvent(ctx, AuthEvent{
+		Type:      EventTokenCreated,
+		UserID:    session.UserID,
+		Provider:  session.Provider.String,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Detail:    "session exchanged for refresh token",
+	})
+	return pair, nil
+}
+
+// RefreshTokens rotates a refresh token: it is marked used and a new pair is
+// issued in the same family. Presenting a token that was already rotated
+// means it leaked, so the whole family is revoked. Like ExchangeSession, it
+// refuses suspended users and accounts scheduled for deletion.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken, ipAddress, userAgent string) (TokenPair, error) {
+	session, err := s.getRefreshSession(ctx, refreshToken)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if session.RotatedAt.Valid {
+		s.revokeReusedFamily(ctx, session, ipAddress, userAgent)
+		return TokenPair{}, ErrRefreshTokenReused
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.queries.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error getting user: %w", err)
+	}
+	if err := CheckSuspension(user); err != nil {
+		return TokenPair{}, err
+	}
+	if user.DeletionScheduledAt.Valid {
+		return TokenPair{}, ErrDeletionScheduled
+	}
+
+	var pair TokenPair
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		rotated, err := q.RotateRefreshSession(ctx, session.ID)
+		if err != nil {
+			return fmt.Errorf("error rotating refresh token: %w", err)
+		}
+		if rotated == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		refresh := sqlc.CreateRefreshSessionParams{
+			UserID:      session.UserID,
+			Provider:    session.Provider,
+			IpAddress:   sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+			UserAgent:   sql.NullString{String: userAgent, Valid: userAgent != ""},
+			TokenFamily: session.TokenFamily,
+			CreatedAt:   session.CreatedAt,
+		}
+		pair, err = s.issueTokenPair(ctx, q, refresh, user.EmailVerifiedAt.Valid)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// Another request rotated the token between our read and update.
+		s.revokeReusedFamily(ctx, session, ipAddress, userAgent)
+		return TokenPair{}, err
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// RevokeRefreshToken ends the login refreshToken belongs to.
+func (s *Service) RevokeRefreshToken(ctx context.Context, refreshToken, ipAddress, userAgent string) error {
+	session, err := s.getRefreshSession(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	if _, err := s.queries.DeleteSessionFamily(ctx, session.TokenFamily); err != nil {
+		return fmt.Errorf("error revoking token family: %w", err)
+	}
+
+	s.RecordAuthEvent(ctx, AuthEvent{
+		Type:      EventLogout,
+		UserID:    session.UserID,
+		Provider:  session.Provider.String,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+	return nil
+}
+
+// VerifyAccessToken checks a JWT access token without a database lookup.
+// Revoked logins, suspensions and scheduled deletions therefore take effect
+// once the access token expires; ExchangeSession and RefreshTokens refuse to
+// issue new ones.
+func (s *Service) VerifyAccessToken(token string) (AccessClaims, error) {
+	return s.jwt.Verify(token, time.Now())
+}
+
+func (s *Service) getRefreshSession(ctx context.Context, refreshToken string) (sqlc.Session, error) {
+	if !strings.HasPrefix(refreshToken, RefreshTokenPrefix) {
+		return sqlc.Session{}, ErrInvalidRefreshToken
+	}
+
+	session, err := s.queries.GetRefreshSession(ctx, hashToken(refreshToken))
+	if err == sql.ErrNoRows {
+		return sqlc.Session{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return sqlc.Session{}, fmt.Errorf("error getting refresh token: %w", err)
+	}
+	return session, nil
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, session sqlc.Session, ipAddress, userAgent string) {
+	log.Printf("Refresh token reuse for user %d, revoking token family", session.UserID)
+	if _, err := s.queries.DeleteSessionFamily(ctx, session.TokenFamily); err != nil {
+		log.Printf("Failed to revoke token family: %v", err)
+	}
+
+	s.RecordAuthEvent(ctx, AuthEvent{
+		Type:      EventSessionRevoked,
+		UserID:    session.UserID,
+		Provider:  session.Provider.String,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Detail:    "refresh token reused",
+	})
+}
+
+// issueTokenPair stores a new refresh token described by refresh and signs a
+// matching access token. The refresh token slides forward like a cookie
+// session and is capped at MaxLifetime from the original login.
+func (s *Service) issueTokenPair(ctx context.Context, q *sqlc.Queries, refresh sqlc.CreateRefreshSessionParams, emailVerified bool) (TokenPair, error) {
+	now := time.Now()
+
+	expiresAt := now.Add(s.policy.IdleTimeout)
+	if deadline := refresh.CreatedAt.Time.Add(s.policy.MaxLifetime); refresh.CreatedAt.Valid && expiresAt.After(deadline) {
+		expiresAt = deadline
+	}
+	if !expiresAt.After(now) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	secret, _, err := newToken()
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error generating refresh token: %w", err)
+	}
+	refreshToken := RefreshTokenPrefix + secret
+
+	refresh.ID = hashToken(refreshToken)
+	refresh.Kind = sessionKindRefresh
+	refresh.ExpiresAt = expiresAt
+	if _, err := q.CreateRefreshSession(ctx, refresh); err != nil {
+		return TokenPair{}, fmt.Errorf("error saving refresh token: %w", err)
+	}
+
+	grants, err := loadGrants(ctx, q, refresh.UserID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	accessToken, err := s.jwt.Sign(refresh.UserID, refresh.TokenFamily.String, emailVerified, grants, now)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.jwt.TTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// exchangeTestSession logs user in with a cookie session and exchanges it for
+// a token pair.
+func exchangeTestSession(t *testing.T, s *Service, user sqlc.User) TokenPair {
+	t.Helper()
+	ctx := context.Background()
+	cookie, err := s.CreateSession(ctx, user, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := s.ExchangeSession(ctx, cookie.ID, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetSessionByID(ctx, cookie.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected the cookie session to be ended, got %v", err)
+	}
+	return pair
+}
+
+func TestRefreshTokensRotates(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	first := exchangeTestSession(t, s, user)
+	second, err := s.RefreshTokens(ctx, first.RefreshToken, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" || second.TokenType != "Bearer" {
+		t.Fatalf("expected a new pair, got %+v", second)
+	}
+
+	firstClaims, err := s.VerifyAccessToken(first.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondClaims, err := s.VerifyAccessToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, _ := secondClaims.UserID(); userID != user.ID || secondClaims.SessionID != firstClaims.SessionID {
+		t.Fatalf("expected the new access token to stay in the family, got %+v", secondClaims)
+	}
+
+	// The family shows up as one login.
+	listed, err := s.ListUserSessions(ctx, user.ID)
+	if err != nil || len(listed) != 1 || SessionKey(listed[0]) != firstClaims.SessionID {
+		t.Fatalf("expected one refresh token login, got %+v: %v", listed, err)
+	}
+
+	if _, err := s.RefreshTokens(ctx, second.RefreshToken, "192.0.2.1", "test"); err != nil {
+		t.Fatalf("expected the new refresh token to work, got %v", err)
+	}
+}
+
+// TestRefreshTokenReuseRevokesFamily replays a rotated refresh token, the way
+// a thief who copied it would.
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	first := exchangeTestSession(t, s, user)
+	second, err := s.RefreshTokens(ctx, first.RefreshToken, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := exchangeTestSession(t, s, user)
+
+	if _, err := s.RefreshTokens(ctx, first.RefreshToken, "198.51.100.7", "thief"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, second.RefreshToken, "192.0.2.1", "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the latest token of the family to be revoked, got %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, other.RefreshToken, "192.0.2.1", "test"); err != nil {
+		t.Fatalf("expected another login to be left alone, got %v", err)
+	}
+
+	events, err := s.ListAuthEvents(ctx, AuthEventFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded bool
+	for _, event := range events {
+		recorded = recorded || (event.EventType == EventSessionRevoked && event.Detail.String == "refresh token reused")
+	}
+	if !recorded {
+		t.Fatalf("expected the reuse to be recorded, got %+v", events)
+	}
+}
+
+func TestExchangeSessionRejectsUnfinishedSessions(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, s, "admin")
+	user := createTestUser(t, s, "ada")
+
+	withTwoFactor := user
+	withTwoFactor.TotpEnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	pending, err := s.CreateSession(ctx, withTwoFactor, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExchangeSession(ctx, pending.ID, "192.0.2.1", "test"); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Fatalf("expected ErrTwoFactorRequired, got %v", err)
+	}
+
+	impersonation, err := s.StartImpersonation(ctx, admin.ID, user.ID, "192.0.2.9", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExchangeSession(ctx, impersonation.ID, "192.0.2.9", "admin"); !errors.Is(err, ErrImpersonating) {
+		t.Fatalf("expected ErrImpersonating, got %v", err)
+	}
+
+	if _, err := s.ExchangeSession(ctx, "no-such-session", "192.0.2.1", "test"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+
+	// Neither session was used up.
+	for _, id := range []string{pending.ID, impersonation.ID} {
+		if _, err := s.GetSessionByID(ctx, id); err != nil {
+			t.Fatalf("expected session to be kept, got %v", err)
+		}
+	}
+}
+
+func TestRefreshTokenExpires(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, s, "ada")
+
+	pair := exchangeTestSession(t, s, user)
+	if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, hashToken(pair.RefreshToken)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RefreshTokens(ctx, pair.RefreshToken, "192.0.2.1", "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+
+	if _, err := s.RefreshTokens(ctx, "hdl_rt_unknown", "192.0.2.1", "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected an unknown token to be rejected, got %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, "not-a-refresh-token", "192.0.2.1", "test"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected a token without the prefix to be rejected, got %v", err)
+	}
+}
+
+// TestTokensRefusedToBlockedUsers checks that suspended accounts and
+// accounts scheduled for deletion cannot get or refresh access tokens. The
+// flags are set on the users row directly, as if the logins had outlived
+// them.
+func TestTokensRefusedToBlockedUsers(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		username string
+		block    func(userID int32) error
+		want     error
+	}{
+		{
+			username: "suspended",
+			block: func(userID int32) error {
+				_, err := s.queries.SuspendUser(ctx, sqlc.SuspendUserParams{
+					ID:               userID,
+					SuspensionReason: sql.NullString{String: "spam", Valid: true},
+				})
+				return err
+			},
+			want: ErrAccountSuspended,
+		},
+		{
+			username: "leaving",
+			block: func(userID int32) error {
+				_, err := s.queries.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+					ID:                  userID,
+					DeletionScheduledAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+				})
+				return err
+			},
+			want: ErrDeletionScheduled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			user := createTestUser(t, s, tt.username)
+
+			cookie, err := s.CreateSession(ctx, user, PasswordProvider, "192.0.2.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pair, err := s.ExchangeSession(ctx, cookie.ID, "192.0.2.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := s.CreateSession(ctx, user, PasswordProvider, "192.0.2.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.block(user.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.RefreshTokens(ctx, pair.RefreshToken, "192.0.2.1", "test"); !errors.Is(err, tt.want) {
+				t.Fatalf("RefreshTokens: expected %v, got %v", tt.want, err)
+			}
+			if _, err := s.ExchangeSession(ctx, other.ID, "192.0.2.1", "test"); !errors.Is(err, tt.want) {
+				t.Fatalf("ExchangeSession: expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to six digits.
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, totpStep(time.Unix(tt.unix, 0))); got != tt.code {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+
+	previous := totpCode(key, totpStep(now)-1)
+	step, ok := matchTOTP(secret, previous, now)
+	if !ok || step != totpStep(now)-1 {
+		t.Fatalf("expected previous step to match, got %d, %v", step, ok)
+	}
+
+	stale := totpCode(key, totpStep(now)-3)
+	if _, ok := matchTOTP(secret, stale, now); ok {
+		t.Fatal("expected code outside the skew window to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Huddle", "jane@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Huddle:jane@example.com?") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Huddle") {
+		t.Fatalf("uri is missing parameters: %s", uri)
+	}
+}
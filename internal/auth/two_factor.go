@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("start two-factor enrollment first")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrNoPendingTwoFactor   = errors.New("no login is waiting for a two-factor code")
+)
+
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is what the user needs to add the account to an
+// authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// BeginTOTPEnrollment stores a new, not yet enabled TOTP secret for the user.
+// Two-factor authentication is only switched on by ConfirmTOTPEnrollment, once
+// the user has proven their app produces valid codes.
+func (s *Service) BeginTOTPEnrollment(ctx context.Context, userID int32) (TOTPEnrollment, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("error getting user: %w", err)
+	}
+	if user.TotpEnabledAt.Valid {
+		return TOTPEnrollment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("error generating totp secret: %w", err)
+	}
+	encrypted, err := s.keyring.Encrypt(secret)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("error encrypting totp secret: %w", err)
+	}
+
+	if err := s.queries.SetUserTOTPSecret(ctx, sqlc.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: encrypted, Valid: true},
+	}); err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("error saving totp secret: %w", err)
+	}
+
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once code matches
+// the enrolled secret, and returns a fresh set of recovery codes. The codes are
+// only stored hashed, so this is the only time they can be shown.
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, userID int32, code string) ([]string, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if user.TotpEnabledAt.Valid {
+		return nil, ErrTwoFactorEnabled
+	}
+	if !user.TotpSecret.Valid {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	secret, err := s.keyring.Decrypt(user.TotpSecret.String)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting totp secret: %w", err)
+	}
+	step, ok := matchTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		enabled, err := q.EnableUserTOTP(ctx, sqlc.EnableUserTOTPParams{
+			ID:           user.ID,
+			TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error enabling totp: %w", err)
+		}
+		if enabled == 0 {
+			return ErrTwoFactorEnabled
+		}
+
+		codes, err = replaceRecoveryCodes(ctx, q, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user after
+// checking a current second factor.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int32, code string) ([]string, error) {
+	user, err := s.enabledTwoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.withTx(ctx, func(q *sqlc.Queries) error {
+		codes, err = replaceRecoveryCodes(ctx, q, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a
+// current second factor, and drops the secret and recovery codes.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID int32, code string) error {
+	user, err := s.enabledTwoFactorUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(q *sqlc.Queries) error {
+		if err := q.DisableUserTOTP(ctx, user.ID); err != nil {
+			return fmt.Errorf("error disabling totp: %w", err)
+		}
+		if err := q.DeleteUserRecoveryCodes(ctx, user.ID); err != nil {
+			return fmt.Errorf("error deleting recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left.
+func (s *Service) CountRecoveryCodes(ctx context.Context, userID int32) (int64, error) {
+	count, err := s.queries.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// CompleteTwoFactor checks code for a session created by CreateSession in the
+// pending state and turns it into a full session.
+func (s *Service) CompleteTwoFactor(ctx context.Context, sessionID, code string) (sqlc.GetSessionByIDRow, error) {
+	session, err := s.queries.GetSessionByID(ctx, sessionID)
+	if err == sql.ErrNoRows {
+		return sqlc.GetSessionByIDRow{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return sqlc.GetSessionByIDRow{}, fmt.Errorf("error getting session: %w", err)
+	}
+	if !session.MfaPending {
+		return sqlc.GetSessionByIDRow{}, ErrNoPendingTwoFactor
+	}
+
+	user, err := s.enabledTwoFactorUser(ctx, session.UserID)
+	if err != nil {
+		return sqlc.GetSessionByIDRow{}, err
+	}
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		return sqlc.GetSessionByIDRow{}, err
+	}
+	if err := s.cancelAccountDeletion(ctx, user, session.IpAddress.String, session.UserAgent.String); err != nil {
+		return sqlc.GetSessionByIDRow{}, err
+	}
+
+	expiresAt := time.Now().Add(s.policy.IdleTimeout)
+	completed, err := s.queries.CompleteSessionMFA(ctx, sqlc.CompleteSessionMFAParams{
+		ID:        session.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return sqlc.GetSessionByIDRow{}, fmt.Errorf("error completing session: %w", err)
+	}
+	if completed == 0 {
+		return sqlc.GetSessionByIDRow{}, ErrNoPendingTwoFactor
+	}
+
+	session.MfaPending = false
+	session.ExpiresAt = expiresAt
+	return session, nil
+}
+
+func (s *Service) enabledTwoFactorUser(ctx context.Context, userID int32) (sqlc.User, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return sqlc.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+	if !user.TotpEnabledAt.Valid || !user.TotpSecret.Valid {
+		return sqlc.User{}, ErrTwoFactorNotEnabled
+	}
+	return user, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. Each TOTP step and each recovery code can only be used once.
+func (s *Service) verifySecondFactor(ctx context.Context, user sqlc.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totpDigits && strings.Trim(code, "0123456789") == "" {
+		secret, err := s.keyring.Decrypt(user.TotpSecret.String)
+		if err != nil {
+			return fmt.Errorf("error decrypting totp secret: %w", err)
+		}
+		step, ok := matchTOTP(secret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		advanced, err := s.queries.AdvanceUserTOTPStep(ctx, sqlc.AdvanceUserTOTPStepParams{
+			Step: sql.NullInt64{Int64: step, Valid: true},
+			ID:   user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("error recording totp step: %w", err)
+		}
+		if advanced == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.queries.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: hashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return fmt.Errorf("error using recovery code: %w", err)
+	}
+	if used == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *sqlc.Queries, userID int32) ([]string, error) {
+	if err := q.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("error generating recovery code: %w", err)
+		}
+		if err := q.CreateRecoveryCode(ctx, sqlc.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		}); err != nil {
+			return nil, fmt.Errorf("error saving recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code such as "k3v9q-7mzpa".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Huddle"
+}
+
+// ReencryptTOTPSecrets rewrites every stored TOTP secret that is still
+// plaintext or sealed with a non-primary key, and returns how many users were
+// updated. It is safe to run repeatedly.
+func (s *Service) ReencryptTOTPSecrets(ctx context.Context) (int, error) {
+	updated := 0
+	var lastID int32
+
+	for {
+		users, err := s.queries.ListUsersWithTOTPSecret(ctx, sqlc.ListUsersWithTOTPSecretParams{
+			ID:    lastID,
+			Limit: 100,
+		})
+		if err != nil {
+			return updated, fmt.Errorf("error listing users: %w", err)
+		}
+		if len(users) == 0 {
+			return updated, nil
+		}
+
+		for _, user := range users {
+			lastID = user.ID
+
+			if !s.keyring.NeedsRotation(user.TotpSecret.String) {
+				continue
+			}
+
+			secret, err := s.keyring.Decrypt(user.TotpSecret.String)
+			if err != nil {
+				return updated, fmt.Errorf("user %d: error decrypting totp secret: %w", user.ID, err)
+			}
+			encrypted, err := s.keyring.Encrypt(secret)
+			if err != nil {
+				return updated, fmt.Errorf("user %d: error encrypting totp secret: %w", user.ID, err)
+			}
+
+			if err := s.queries.SetUserTOTPSecret(ctx, sqlc.SetUserTOTPSecretParams{
+				ID:         user.ID,
+				TotpSecret: sql.NullString{String: encrypted, Valid: true},
+			}); err != nil {
+				return updated, fmt.Errorf("user %d: error saving totp secret: %w", user.ID, err)
+			}
+			updated++
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// enableTestTwoFactor enrolls user in two-factor authentication, confirming
+// with the code of the previous step so the current one is still unused. It
+// returns the user with two-factor on, the TOTP key and the recovery codes.
+func enableTestTwoFactor(t *testing.T, s *Service, user sqlc.User) (sqlc.User, []byte, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	enrollment, err := s.BeginTOTPEnrollment(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := s.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(key, totpStep(time.Now())-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := s.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enabled, key, codes
+}
+
+func pendingTestSession(t *testing.T, s *Service, user sqlc.User) sqlc.Session {
+	t.Helper()
+	session, err := s.CreateSession(context.Background(), user, PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.MfaPending {
+		t.Fatalf("expected a pending session for a user with two-factor on, got %+v", session)
+	}
+	return session
+}
+
+func TestCompleteTwoFactor(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user, key, _ := enableTestTwoFactor(t, s, createTestUser(t, s, "ada"))
+
+	session := pendingTestSession(t, s, user)
+	if _, err := s.CompleteTwoFactor(ctx, session.ID, "000000x"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+
+	completed, err := s.CompleteTwoFactor(ctx, session.ID, totpCode(key, totpStep(time.Now())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.MfaPending || !completed.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("expected a full session, got %+v", completed)
+	}
+	stored, err := s.GetSessionByID(ctx, session.ID)
+	if err != nil || stored.MfaPending {
+		t.Fatalf("expected the pending flag to be cleared, got %+v: %v", stored, err)
+	}
+
+	if _, err := s.CompleteTwoFactor(ctx, session.ID, totpCode(key, totpStep(time.Now())+1)); !errors.Is(err, ErrNoPendingTwoFactor) {
+		t.Fatalf("expected ErrNoPendingTwoFactor, got %v", err)
+	}
+}
+
+func TestTOTPStepCannotBeReplayed(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user, key, _ := enableTestTwoFactor(t, s, createTestUser(t, s, "ada"))
+
+	first := pendingTestSession(t, s, user)
+	second := pendingTestSession(t, s, user)
+
+	code := totpCode(key, totpStep(time.Now()))
+	if _, err := s.CompleteTwoFactor(ctx, first.ID, code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompleteTwoFactor(ctx, second.ID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected a replayed code to get ErrInvalidTwoFactorCode, got %v", err)
+	}
+	// Steps before the last one used are spent too.
+	if _, err := s.CompleteTwoFactor(ctx, second.ID, totpCode(key, totpStep(time.Now())-1)); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected an older code to get ErrInvalidTwoFactorCode, got %v", err)
+	}
+
+	stored, err := s.GetSessionByID(ctx, second.ID)
+	if err != nil || !stored.MfaPending {
+		t.Fatalf("expected the second login to stay pending, got %+v: %v", stored, err)
+	}
+	if _, err := s.CompleteTwoFactor(ctx, second.ID, totpCode(key, totpStep(time.Now())+1)); err != nil {
+		t.Fatalf("expected the next step to work, got %v", err)
+	}
+}
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	user, _, codes := enableTestTwoFactor(t, s, createTestUser(t, s, "ada"))
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	first := pendingTestSession(t, s, user)
+	second := pendingTestSession(t, s, user)
+
+	// Codes are accepted the way people type them.
+	if _, err := s.CompleteTwoFactor(ctx, first.ID, " "+strings.ToUpper(codes[0])+" "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompleteTwoFactor(ctx, second.ID, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected a used recovery code to get ErrInvalidTwoFactorCode, got %v", err)
+	}
+	if remaining, err := s.CountRecoveryCodes(ctx, user.ID); err != nil || remaining != recoveryCodeCount-1 {
+		t.Fatalf("expected %d recovery codes left, got %d: %v", recoveryCodeCount-1, remaining, err)
+	}
+
+	if _, err := s.CompleteTwoFactor(ctx, second.ID, codes[1]); err != nil {
+		t.Fatalf("expected another recovery code to work, got %v", err)
+	}
+}
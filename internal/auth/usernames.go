@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"huddle-backend/internal/profiles"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markbates/goth"
+)
+
+const (
+	// maxUsernameAttempts bounds the retries when generated usernames keep
+	// colliding with existing ones.
+	maxUsernameAttempts = 10
+
+	fallbackUsername = "user"
+)
+
+// usernameBase derives a username from the provider profile: the nickname,
+// else the display name, else first and last name. It is reduced to the
+// characters profile.ValidateUsernameFormat accepts. The email address is
+// never used, so it does not leak through public usernames.
+func usernameBase(gothUser goth.User) string {
+	for _, source := range []string{
+		gothUser.NickName,
+		gothUser.Name,
+		strings.TrimSpace(gothUser.FirstName + " " + gothUser.LastName),
+	} {
+		if base := normalizeUsername(source); base != "" {
+			return base
+		}
+	}
+	return fallbackUsername
+}
+
+// normalizeUsername lower-cases value, turns separators into underscores and
+// drops anything else that is not allowed in a username. Results that are
+// still too short are rejected with "".
+func normalizeUsername(value string) string {
+	var b strings.Builder
+	lastSeparator := true
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			lastSeparator = false
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			if !lastSeparator {
+				b.WriteByte('_')
+				lastSeparator = true
+			}
+		}
+	}
+
+	username := strings.Trim(b.String(), "_")
+	if len(username) > profile.MaxUsernameLength {
+		username = strings.TrimRight(username[:profile.MaxUsernameLength], "_")
+	}
+	if profile.ValidateUsernameFormat(username) != nil {
+		return ""
+	}
+	return username
+}
+
+// usernameCandidate returns the username to try on the given attempt: the
+// base itself, then a few numbered variants and finally random suffixes,
+// which stay cheap when a popular name is taken many times over.
+func usernameCandidate(base string, attempt int) (string, error) {
+	var suffix string
+	switch {
+	case attempt == 0:
+		return base, nil
+	case attempt < 4:
+		suffix = strconv.Itoa(attempt + 1)
+	default:
+		n, err := rand.Int(rand.Reader, big.NewInt(900000))
+		if err != nil {
+			return "", err
+		}
+		suffix = strconv.FormatInt(n.Int64()+100000, 10)
+	}
+
+	if len(base)+len(suffix) > profile.MaxUsernameLength {
+		base = base[:profile.MaxUsernameLength-len(suffix)]
+	}
+	return base + suffix, nil
+}
+
+func isUsernameCollision(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "users_username_key"
+}
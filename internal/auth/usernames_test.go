@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"huddle-backend/internal/profiles"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markbates/goth"
+)
+
+func TestUsernameBase(t *testing.T) {
+	tests := []struct {
+		user goth.User
+		want string
+	}{
+		{goth.User{NickName: "John", Email: "john@example.com"}, "john"},
+		{goth.User{NickName: "jo", Name: "John Smith"}, "john_smith"},
+		{goth.User{Name: "  Zoë   O'Brien-Smith "}, "zo_obrien_smith"},
+		{goth.User{FirstName: "Ada", LastName: "Lovelace"}, "ada_lovelace"},
+		{goth.User{NickName: "first.last+tag"}, "first_lasttag"},
+		{goth.User{NickName: strings.Repeat("a", 40)}, strings.Repeat("a", profile.MaxUsernameLength)},
+		{goth.User{NickName: "李小龙", Email: "bruce@example.com"}, fallbackUsername},
+		{goth.User{Email: "someone@example.com"}, fallbackUsername},
+	}
+
+	for _, tt := range tests {
+		if got := usernameBase(tt.user); got != tt.want {
+			t.Errorf("usernameBase(%+v) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestUsernameCandidate(t *testing.T) {
+	base := strings.Repeat("b", profile.MaxUsernameLength)
+
+	seen := map[string]bool{}
+	for attempt := 0; attempt < maxUsernameAttempts; attempt++ {
+		candidate, err := usernameCandidate(base, attempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := profile.ValidateUsernameFormat(candidate); err != nil {
+			t.Fatalf("attempt %d: %q is invalid: %v", attempt, candidate, err)
+		}
+		if seen[candidate] {
+			t.Fatalf("attempt %d repeated %q", attempt, candidate)
+		}
+		seen[candidate] = true
+	}
+
+	if got, _ := usernameCandidate("john", 1); got != "john2" {
+		t.Fatalf("second candidate = %q, want john2", got)
+	}
+}
+
+func TestIsUsernameCollision(t *testing.T) {
+	username := fmt.Errorf("error creating user: %w", &pgconn.PgError{Code: uniqueViolation, ConstraintName: "users_username_key"})
+	email := fmt.Errorf("error creating user: %w", &pgconn.PgError{Code: uniqueViolation, ConstraintName: "users_email_key"})
+
+	if !isUsernameCollision(username) {
+		t.Fatal("expected username constraint violation to be a collision")
+	}
+	if isUsernameCollision(email) || isUsernameCollision(nil) {
+		t.Fatal("expected other errors not to be collisions")
+	}
+}
@@ -0,0 +1,125 @@
+// Package dbtest gives tests a throwaway Postgres database with every
+// migration applied. One container is started per test binary; each test
+// gets its own database, cloned from a migrated template. Tests are skipped
+// when Docker is not available.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const templateDatabase = "huddle"
+
+var (
+	startOnce sync.Once
+	startErr  error
+	baseURL   *url.URL
+	databases atomic.Int64
+)
+
+// New returns a connection to a fresh, migrated database and its queries.
+// The container, with every database in it, is removed once the tests end.
+func New(t *testing.T) (*sql.DB, *sqlc.Queries) {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	startOnce.Do(func() { startErr = start() })
+	if startErr != nil {
+		t.Fatalf("could not start postgres container: %v", startErr)
+	}
+
+	ctx := context.Background()
+	admin := open(t, "postgres")
+	name := fmt.Sprintf("test_%d", databases.Add(1))
+	if _, err := admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", name, templateDatabase)); err != nil {
+		t.Fatalf("could not create database: %v", err)
+	}
+	admin.Close()
+
+	db := open(t, name)
+	t.Cleanup(func() { db.Close() })
+	return db, sqlc.New(db)
+}
+
+func start() error {
+	ctx := context.Background()
+	container, err := postgres.Run(ctx,
+		"postgres:latest",
+		postgres.WithDatabase(templateDatabase),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second)),
+	)
+	if err != nil {
+		return err
+	}
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		return err
+	}
+	if baseURL, err = url.Parse(connStr); err != nil {
+		return err
+	}
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return err
+	}
+	// The template cannot be cloned while anyone is connected to it.
+	defer db.Close()
+	return migrate(ctx, db)
+}
+
+// migrate applies the up migrations in order, the way migrate-up does.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations")
+	migrations, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(migrations)
+
+	for _, migration := range migrations {
+		data, err := os.ReadFile(migration)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, string(data)); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(migration), err)
+		}
+	}
+	return nil
+}
+
+func open(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	connURL := *baseURL
+	connURL.Path = "/" + name
+	db, err := sql.Open("pgx", connURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
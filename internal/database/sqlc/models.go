@@ -9,6 +9,79 @@ import (
 	"time"
 )
 
+type AdminAction struct {
+	ID           int64          `json:"id"`
+	AdminID      sql.NullInt32  `json:"admin_id"`
+	TargetUserID sql.NullInt32  `json:"target_user_id"`
+	TargetEmail  sql.NullString `json:"target_email"`
+	Action       string         `json:"action"`
+	Detail       sql.NullString `json:"detail"`
+	IpAddress    sql.NullString `json:"ip_address"`
+	UserAgent    sql.NullString `json:"user_agent"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type AuthEvent struct {
+	ID        int64          `json:"id"`
+	UserID    sql.NullInt32  `json:"user_id"`
+	EventType string         `json:"event_type"`
+	Provider  sql.NullString `json:"provider"`
+	Email     sql.NullString `json:"email"`
+	IpAddress sql.NullString `json:"ip_address"`
+	UserAgent sql.NullString `json:"user_agent"`
+	Detail    sql.NullString `json:"detail"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type DataExport struct {
+	ID           int64          `json:"id"`
+	UserID       sql.NullInt32  `json:"user_id"`
+	Status       string         `json:"status"`
+	StorageKey   sql.NullString `json:"storage_key"`
+	SizeBytes    sql.NullInt64  `json:"size_bytes"`
+	ExpiresAt    sql.NullTime   `json:"expires_at"`
+	DownloadedAt sql.NullTime   `json:"downloaded_at"`
+	CompletedAt  sql.NullTime   `json:"completed_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type EmailVerificationToken struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	Email     string       `json:"email"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type Permission struct {
+	ID          int32          `json:"id"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+}
+
+type PersonalAccessToken struct {
+	ID          int32        `json:"id"`
+	UserID      int32        `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenPrefix string       `json:"token_prefix"`
+	TokenHash   string       `json:"token_hash"`
+	Scopes      string       `json:"scopes"`
+	LastUsedAt  sql.NullTime `json:"last_used_at"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+}
+
 type Profile struct {
 	ID          int32          `json:"id"`
 	UserID      int32          `json:"user_id"`
@@ -20,34 +93,94 @@ type Profile struct {
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
 }
 
+type RateLimit struct {
+	Key         string       `json:"key"`
+	Tokens      float64      `json:"tokens"`
+	Failures    int32        `json:"failures"`
+	LockedUntil sql.NullTime `json:"locked_until"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
+}
+
+type Role struct {
+	ID          int32          `json:"id"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+}
+
+type RolePermission struct {
+	RoleID       int32 `json:"role_id"`
+	PermissionID int32 `json:"permission_id"`
+}
+
 type Session struct {
-	ID        string         `json:"id"`
-	UserID    int32          `json:"user_id"`
-	Provider  sql.NullString `json:"provider"`
-	IpAddress sql.NullString `json:"ip_address"`
-	UserAgent sql.NullString `json:"user_agent"`
-	ExpiresAt time.Time      `json:"expires_at"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ID             string         `json:"id"`
+	UserID         int32          `json:"user_id"`
+	Provider       sql.NullString `json:"provider"`
+	IpAddress      sql.NullString `json:"ip_address"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	ExpiresAt      time.Time      `json:"expires_at"`
+	CreatedAt      sql.NullTime   `json:"created_at"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	MfaPending     bool           `json:"mfa_pending"`
+	Kind           string         `json:"kind"`
+	TokenFamily    sql.NullString `json:"token_family"`
+	RotatedAt      sql.NullTime   `json:"rotated_at"`
+	Roles          string         `json:"roles"`
+	Permissions    string         `json:"permissions"`
+	ImpersonatorID sql.NullInt32  `json:"impersonator_id"`
+}
+
+type TwoFactorRecoveryCode struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
 
 type User struct {
+	ID                  int32          `json:"id"`
+	Username            string         `json:"username"`
+	Email               string         `json:"email"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	AvatarUrl           sql.NullString `json:"avatar_url"`
+	Provider            sql.NullString `json:"provider"`
+	ProviderUserID      sql.NullString `json:"provider_user_id"`
+	Name                sql.NullString `json:"name"`
+	FirstName           sql.NullString `json:"first_name"`
+	LastName            sql.NullString `json:"last_name"`
+	NickName            sql.NullString `json:"nick_name"`
+	Description         sql.NullString `json:"description"`
+	Location            sql.NullString `json:"location"`
+	CreatedAt           sql.NullTime   `json:"created_at"`
+	UpdatedAt           sql.NullTime   `json:"updated_at"`
+	EmailVerifiedAt     sql.NullTime   `json:"email_verified_at"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabledAt       sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep        sql.NullInt64  `json:"totp_last_step"`
+	SuspendedAt         sql.NullTime   `json:"suspended_at"`
+	SuspendedUntil      sql.NullTime   `json:"suspended_until"`
+	SuspensionReason    sql.NullString `json:"suspension_reason"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
+}
+
+type UserIdentity struct {
 	ID             int32          `json:"id"`
-	Username       string         `json:"username"`
-	Email          string         `json:"email"`
-	PasswordHash   sql.NullString `json:"password_hash"`
-	AvatarUrl      sql.NullString `json:"avatar_url"`
-	Provider       sql.NullString `json:"provider"`
-	ProviderUserID sql.NullString `json:"provider_user_id"`
+	UserID         int32          `json:"user_id"`
+	Provider       string         `json:"provider"`
+	ProviderUserID string         `json:"provider_user_id"`
+	Email          sql.NullString `json:"email"`
 	AccessToken    sql.NullString `json:"access_token"`
 	RefreshToken   sql.NullString `json:"refresh_token"`
 	ExpiresAt      sql.NullTime   `json:"expires_at"`
-	Name           sql.NullString `json:"name"`
-	FirstName      sql.NullString `json:"first_name"`
-	LastName       sql.NullString `json:"last_name"`
-	NickName       sql.NullString `json:"nick_name"`
-	Description    sql.NullString `json:"description"`
-	Location       sql.NullString `json:"location"`
 	CreatedAt      sql.NullTime   `json:"created_at"`
 	UpdatedAt      sql.NullTime   `json:"updated_at"`
 }
+
+type UserRole struct {
+	UserID    int32         `json:"user_id"`
+	RoleID    int32         `json:"role_id"`
+	GrantedBy sql.NullInt32 `json:"granted_by"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+}
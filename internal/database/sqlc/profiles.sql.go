@@ -119,6 +119,13 @@ func (q *Queries) GetProfileByUsername(ctx context.Context, username string) (Pr
 
 const listProfiles = `-- name: ListProfiles :many
 SELECT id, user_id, username, display_name, bio, website, created_at, updated_at FROM profiles
+WHERE NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = profiles.user_id
+      AND (users.deletion_scheduled_at IS NOT NULL
+        OR (users.suspended_at IS NOT NULL
+          AND (users.suspended_until IS NULL OR users.suspended_until > NOW())))
+)
 ORDER BY created_at DESC
     LIMIT $1 OFFSET $2
 `
@@ -163,6 +170,13 @@ func (q *Queries) ListProfiles(ctx context.Context, arg ListProfilesParams) ([]P
 const searchProfilesByUsername = `-- name: SearchProfilesByUsername :many
 SELECT id, user_id, username, display_name, bio, website, created_at, updated_at FROM profiles
 WHERE username ILIKE $1
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = profiles.user_id
+      AND (users.deletion_scheduled_at IS NOT NULL
+        OR (users.suspended_at IS NOT NULL
+          AND (users.suspended_until IS NULL OR users.suspended_until > NOW())))
+  )
 ORDER BY username
     LIMIT $2 OFFSET $3
 `
@@ -6,32 +6,123 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	AdvanceUserTOTPStep(ctx context.Context, arg AdvanceUserTOTPStepParams) (int64, error)
+	CancelUserDeletion(ctx context.Context, id int32) (int64, error)
 	CheckUsernameExists(ctx context.Context, username string) (bool, error)
+	ClaimDataExportDownload(ctx context.Context, id int64) (DataExport, error)
+	ClearDataExportStorage(ctx context.Context, id int64) error
+	CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) (int64, error)
+	CompleteSessionMFA(ctx context.Context, arg CompleteSessionMFAParams) (int64, error)
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (EmailVerificationToken, error)
+	ConsumePasswordResetToken(ctx context.Context, id int32) (int64, error)
+	CountRoleMembers(ctx context.Context, roleID int32) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int32) (int64, error)
+	CountUserPersonalAccessTokens(ctx context.Context, userID int32) (int64, error)
+	CreateAdminAction(ctx context.Context, arg CreateAdminActionParams) error
+	CreateAuthEvent(ctx context.Context, arg CreateAuthEventParams) error
+	CreateDataExport(ctx context.Context, userID sql.NullInt32) (DataExport, error)
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error)
+	CreateImpersonationSession(ctx context.Context, arg CreateImpersonationSessionParams) (Session, error)
 	CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (User, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreatePasswordUser(ctx context.Context, arg CreatePasswordUserParams) (User, error)
+	CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (PersonalAccessToken, error)
 	CreateProfile(ctx context.Context, arg CreateProfileParams) (Profile, error)
+	CreateRateLimit(ctx context.Context, key string) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefreshSession(ctx context.Context, arg CreateRefreshSessionParams) (Session, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
-	DeleteExpiredSessions(ctx context.Context) error
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	DeleteAuthEventsBefore(ctx context.Context, createdAt time.Time) (int64, error)
+	DeleteDataExport(ctx context.Context, id int64) error
+	DeleteExpiredEmailVerificationTokens(ctx context.Context) (int64, error)
+	DeleteExpiredPasswordResetTokens(ctx context.Context) (int64, error)
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+	DeleteImpersonatorSessions(ctx context.Context, impersonatorID sql.NullInt32) error
+	DeleteOtherUserSessions(ctx context.Context, arg DeleteOtherUserSessionsParams) (int64, error)
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
 	DeleteProfile(ctx context.Context, userID int32) error
+	DeleteScheduledUser(ctx context.Context, id int32) (int64, error)
 	DeleteSession(ctx context.Context, id string) error
+	DeleteSessionFamily(ctx context.Context, tokenFamily sql.NullString) (int64, error)
 	DeleteUser(ctx context.Context, id int32) error
+	DeleteUserEmailVerificationTokens(ctx context.Context, userID int32) error
+	DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) (int64, error)
+	DeleteUserPasswordResetTokens(ctx context.Context, userID int32) error
+	DeleteUserPersonalAccessTokens(ctx context.Context, userID int32) error
+	DeleteUserRecoveryCodes(ctx context.Context, userID int32) error
 	DeleteUserSessions(ctx context.Context, userID int32) error
+	DisableUserTOTP(ctx context.Context, id int32) error
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (int64, error)
+	ExtendSession(ctx context.Context, arg ExtendSessionParams) error
+	FailDataExport(ctx context.Context, id int64) error
+	GetDataExport(ctx context.Context, id int64) (DataExport, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (GetPersonalAccessTokenByHashRow, error)
 	GetProfileByUserID(ctx context.Context, userID int32) (Profile, error)
 	GetProfileByUsername(ctx context.Context, username string) (Profile, error)
+	GetRateLimitForUpdate(ctx context.Context, key string) (GetRateLimitForUpdateRow, error)
+	GetRefreshSession(ctx context.Context, id string) (Session, error)
+	GetRoleByName(ctx context.Context, name string) (Role, error)
 	GetSessionByID(ctx context.Context, id string) (GetSessionByIDRow, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id int32) (User, error)
 	GetUserByProviderID(ctx context.Context, arg GetUserByProviderIDParams) (User, error)
+	GetUserIdentityByProvider(ctx context.Context, arg GetUserIdentityByProviderParams) (UserIdentity, error)
+	GetUserPermissionNames(ctx context.Context, userID int32) ([]string, error)
+	GetUserRoleNames(ctx context.Context, userID int32) ([]string, error)
 	GetUserSessions(ctx context.Context, userID int32) ([]Session, error)
+	GetUserSuspension(ctx context.Context, id int32) (GetUserSuspensionRow, error)
+	GetValidPasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	GrantUserRole(ctx context.Context, arg GrantUserRoleParams) (int64, error)
+	ListAdminActions(ctx context.Context, arg ListAdminActionsParams) ([]AdminAction, error)
+	ListAuthEvents(ctx context.Context, arg ListAuthEventsParams) ([]AuthEvent, error)
+	ListDataExportsToPurge(ctx context.Context, limit int32) ([]DataExport, error)
+	ListPendingDataExports(ctx context.Context, limit int32) ([]DataExport, error)
 	ListProfiles(ctx context.Context, arg ListProfilesParams) ([]Profile, error)
+	ListRolePermissions(ctx context.Context) ([]ListRolePermissionsRow, error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	ListUserAdminActions(ctx context.Context, targetUserID sql.NullInt32) ([]AdminAction, error)
+	ListUserAuthEvents(ctx context.Context, userID sql.NullInt32) ([]AuthEvent, error)
+	ListUserDataExports(ctx context.Context, userID sql.NullInt32) ([]DataExport, error)
+	ListUserEmailVerificationTokens(ctx context.Context, userID int32) ([]EmailVerificationToken, error)
+	ListUserIdentities(ctx context.Context, userID int32) ([]UserIdentity, error)
+	ListUserIdentitiesWithOAuthTokens(ctx context.Context, arg ListUserIdentitiesWithOAuthTokensParams) ([]UserIdentity, error)
+	ListUserPasswordResetTokens(ctx context.Context, userID int32) ([]PasswordResetToken, error)
+	ListUserPersonalAccessTokens(ctx context.Context, userID int32) ([]PersonalAccessToken, error)
+	ListUserRecoveryCodes(ctx context.Context, userID int32) ([]TwoFactorRecoveryCode, error)
+	ListUserRoleGrants(ctx context.Context, userID int32) ([]ListUserRoleGrantsRow, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	ListUsersDueForDeletion(ctx context.Context, limit int32) ([]ListUsersDueForDeletionRow, error)
+	ListUsersWithTOTPSecret(ctx context.Context, arg ListUsersWithTOTPSecretParams) ([]ListUsersWithTOTPSecretRow, error)
+	LockRoleByName(ctx context.Context, name string) (Role, error)
+	LockUserByID(ctx context.Context, id int32) (User, error)
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (int64, error)
+	PurgeRateLimits(ctx context.Context, arg PurgeRateLimitsParams) (int64, error)
+	ReleaseDataExportDownload(ctx context.Context, id int64) error
+	RevokeUserRole(ctx context.Context, arg RevokeUserRoleParams) (int64, error)
+	RotateRefreshSession(ctx context.Context, id string) (int64, error)
+	ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (int64, error)
+	ScrubAdminActionActor(ctx context.Context, adminID sql.NullInt32) error
+	ScrubAdminActionTarget(ctx context.Context, targetUserID sql.NullInt32) error
+	ScrubUserAuthEvents(ctx context.Context, arg ScrubUserAuthEventsParams) error
 	SearchProfilesByUsername(ctx context.Context, arg SearchProfilesByUsernameParams) ([]Profile, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error
+	SuspendUser(ctx context.Context, arg SuspendUserParams) (int64, error)
+	TouchPersonalAccessToken(ctx context.Context, id int32) error
+	UnsuspendUser(ctx context.Context, id int32) (int64, error)
 	UpdateProfile(ctx context.Context, arg UpdateProfileParams) (Profile, error)
+	UpdateRateLimit(ctx context.Context, arg UpdateRateLimitParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
-	UpdateUserOAuthTokens(ctx context.Context, arg UpdateUserOAuthTokensParams) (User, error)
+	UpdateUserIdentityTokens(ctx context.Context, arg UpdateUserIdentityTokensParams) (UserIdentity, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpdateUserSessionGrants(ctx context.Context, arg UpdateUserSessionGrantsParams) error
 	UpdateUsername(ctx context.Context, arg UpdateUsernameParams) (Profile, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
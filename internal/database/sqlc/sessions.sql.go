@@ -11,26 +11,167 @@ import (
 	"time"
 )
 
-const createSession = `-- name: CreateSession :one
+const completeSessionMFA = `-- name: CompleteSessionMFA :execrows
+UPDATE sessions
+SET mfa_pending = FALSE, expires_at = $2, updated_at = NOW()
+WHERE id = $1 AND mfa_pending = TRUE AND expires_at > NOW()
+`
+
+type CompleteSessionMFAParams struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CompleteSessionMFA(ctx context.Context, arg CompleteSessionMFAParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, completeSessionMFA, arg.ID, arg.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createImpersonationSession = `-- name: CreateImpersonationSession :one
 INSERT INTO sessions (
     id,
     user_id,
+    impersonator_id,
     provider,
     ip_address,
     user_agent,
     expires_at
 )
-VALUES ($1, $2, $3, $4, $5, $6)
-    RETURNING id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at, mfa_pending, kind, token_family, rotated_at, roles, permissions, impersonator_id
+`
+
+type CreateImpersonationSessionParams struct {
+	ID             string         `json:"id"`
+	UserID         int32          `json:"user_id"`
+	ImpersonatorID sql.NullInt32  `json:"impersonator_id"`
+	Provider       sql.NullString `json:"provider"`
+	IpAddress      sql.NullString `json:"ip_address"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	ExpiresAt      time.Time      `json:"expires_at"`
+}
+
+func (q *Queries) CreateImpersonationSession(ctx context.Context, arg CreateImpersonationSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createImpersonationSession,
+		arg.ID,
+		arg.UserID,
+		arg.ImpersonatorID,
+		arg.Provider,
+		arg.IpAddress,
+		arg.UserAgent,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.IpAddress,
+		&i.UserAgent,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MfaPending,
+		&i.Kind,
+		&i.TokenFamily,
+		&i.RotatedAt,
+		&i.Roles,
+		&i.Permissions,
+		&i.ImpersonatorID,
+	)
+	return i, err
+}
+
+const createRefreshSession = `-- name: CreateRefreshSession :one
+INSERT INTO sessions (
+    id,
+    user_id,
+    provider,
+    ip_address,
+    user_agent,
+    expires_at,
+    kind,
+    token_family,
+    created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at, mfa_pending, kind, token_family, rotated_at, roles, permissions, impersonator_id
+`
+
+type CreateRefreshSessionParams struct {
+	ID          string         `json:"id"`
+	UserID      int32          `json:"user_id"`
+	Provider    sql.NullString `json:"provider"`
+	IpAddress   sql.NullString `json:"ip_address"`
+	UserAgent   sql.NullString `json:"user_agent"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	Kind        string         `json:"kind"`
+	TokenFamily sql.NullString `json:"token_family"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+}
+
+func (q *Queries) CreateRefreshSession(ctx context.Context, arg CreateRefreshSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createRefreshSession,
+		arg.ID,
+		arg.UserID,
+		arg.Provider,
+		arg.IpAddress,
+		arg.UserAgent,
+		arg.ExpiresAt,
+		arg.Kind,
+		arg.TokenFamily,
+		arg.CreatedAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.IpAddress,
+		&i.UserAgent,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MfaPending,
+		&i.Kind,
+		&i.TokenFamily,
+		&i.RotatedAt,
+		&i.Roles,
+		&i.Permissions,
+		&i.ImpersonatorID,
+	)
+	return i, err
+}
+
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions (
+    id,
+    user_id,
+    provider,
+    ip_address,
+    user_agent,
+    expires_at,
+    mfa_pending,
+    roles,
+    permissions
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at, mfa_pending, kind, token_family, rotated_at, roles, permissions, impersonator_id
 `
 
 type CreateSessionParams struct {
-	ID        string         `json:"id"`
-	UserID    int32          `json:"user_id"`
-	Provider  sql.NullString `json:"provider"`
-	IpAddress sql.NullString `json:"ip_address"`
-	UserAgent sql.NullString `json:"user_agent"`
-	ExpiresAt time.Time      `json:"expires_at"`
+	ID          string         `json:"id"`
+	UserID      int32          `json:"user_id"`
+	Provider    sql.NullString `json:"provider"`
+	IpAddress   sql.NullString `json:"ip_address"`
+	UserAgent   sql.NullString `json:"user_agent"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	MfaPending  bool           `json:"mfa_pending"`
+	Roles       string         `json:"roles"`
+	Permissions string         `json:"permissions"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -41,6 +182,9 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.IpAddress,
 		arg.UserAgent,
 		arg.ExpiresAt,
+		arg.MfaPending,
+		arg.Roles,
+		arg.Permissions,
 	)
 	var i Session
 	err := row.Scan(
@@ -52,19 +196,57 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MfaPending,
+		&i.Kind,
+		&i.TokenFamily,
+		&i.RotatedAt,
+		&i.Roles,
+		&i.Permissions,
+		&i.ImpersonatorID,
 	)
 	return i, err
 }
 
-const deleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
 DELETE FROM sessions WHERE expires_at < NOW()
 `
 
-func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteExpiredSessions)
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteImpersonatorSessions = `-- name: DeleteImpersonatorSessions :exec
+DELETE FROM sessions WHERE impersonator_id = $1
+`
+
+func (q *Queries) DeleteImpersonatorSessions(ctx context.Context, impersonatorID sql.NullInt32) error {
+	_, err := q.db.ExecContext(ctx, deleteImpersonatorSessions, impersonatorID)
 	return err
 }
 
+const deleteOtherUserSessions = `-- name: DeleteOtherUserSessions :execrows
+DELETE FROM sessions
+WHERE user_id = $1 AND id <> $2 AND (token_family IS NULL OR token_family <> $2)
+  AND impersonator_id IS NULL
+`
+
+type DeleteOtherUserSessionsParams struct {
+	UserID int32  `json:"user_id"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) DeleteOtherUserSessions(ctx context.Context, arg DeleteOtherUserSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOtherUserSessions, arg.UserID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteSession = `-- name: DeleteSession :exec
 DELETE FROM sessions WHERE id = $1
 `
@@ -74,8 +256,21 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 	return err
 }
 
+const deleteSessionFamily = `-- name: DeleteSessionFamily :execrows
+DELETE FROM sessions
+WHERE token_family = $1
+`
+
+func (q *Queries) DeleteSessionFamily(ctx context.Context, tokenFamily sql.NullString) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSessionFamily, tokenFamily)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUserSessions = `-- name: DeleteUserSessions :exec
-DELETE FROM sessions WHERE user_id = $1
+DELETE FROM sessions WHERE user_id = $1 OR impersonator_id = $1
 `
 
 func (q *Queries) DeleteUserSessions(ctx context.Context, userID int32) error {
@@ -83,40 +278,96 @@ func (q *Queries) DeleteUserSessions(ctx context.Context, userID int32) error {
 	return err
 }
 
+const extendSession = `-- name: ExtendSession :exec
+UPDATE sessions
+SET expires_at = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type ExtendSessionParams struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) ExtendSession(ctx context.Context, arg ExtendSessionParams) error {
+	_, err := q.db.ExecContext(ctx, extendSession, arg.ID, arg.ExpiresAt)
+	return err
+}
+
+const getRefreshSession = `-- name: GetRefreshSession :one
+SELECT id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at, mfa_pending, kind, token_family, rotated_at, roles, permissions, impersonator_id FROM sessions
+WHERE id = $1 AND kind = 'refresh'
+`
+
+func (q *Queries) GetRefreshSession(ctx context.Context, id string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.IpAddress,
+		&i.UserAgent,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MfaPending,
+		&i.Kind,
+		&i.TokenFamily,
+		&i.RotatedAt,
+		&i.Roles,
+		&i.Permissions,
+		&i.ImpersonatorID,
+	)
+	return i, err
+}
+
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT s.id, s.user_id, s.provider, s.ip_address, s.user_agent, s.expires_at, s.created_at, s.updated_at, u.id, u.username, u.email, u.password_hash, u.avatar_url, u.provider, u.provider_user_id, u.access_token, u.refresh_token, u.expires_at, u.name, u.first_name, u.last_name, u.nick_name, u.description, u.location, u.created_at, u.updated_at
+SELECT s.id, s.user_id, s.provider, s.ip_address, s.user_agent, s.expires_at, s.created_at, s.updated_at, s.mfa_pending, s.kind, s.token_family, s.rotated_at, s.roles, s.permissions, s.impersonator_id, u.id, u.username, u.email, u.password_hash, u.avatar_url, u.provider, u.provider_user_id, u.name, u.first_name, u.last_name, u.nick_name, u.description, u.location, u.created_at, u.updated_at, u.email_verified_at, u.totp_secret, u.totp_enabled_at, u.totp_last_step, u.suspended_at, u.suspended_until, u.suspension_reason, u.deletion_scheduled_at
 FROM sessions s
          JOIN users u ON s.user_id = u.id
-WHERE s.id = $1 AND s.expires_at > NOW()
+WHERE s.id = $1 AND s.kind = 'cookie' AND s.expires_at > NOW()
 `
 
 type GetSessionByIDRow struct {
-	ID             string         `json:"id"`
-	UserID         int32          `json:"user_id"`
-	Provider       sql.NullString `json:"provider"`
-	IpAddress      sql.NullString `json:"ip_address"`
-	UserAgent      sql.NullString `json:"user_agent"`
-	ExpiresAt      time.Time      `json:"expires_at"`
-	CreatedAt      sql.NullTime   `json:"created_at"`
-	UpdatedAt      sql.NullTime   `json:"updated_at"`
-	ID_2           int32          `json:"id_2"`
-	Username       string         `json:"username"`
-	Email          string         `json:"email"`
-	PasswordHash   sql.NullString `json:"password_hash"`
-	AvatarUrl      sql.NullString `json:"avatar_url"`
-	Provider_2     sql.NullString `json:"provider_2"`
-	ProviderUserID sql.NullString `json:"provider_user_id"`
-	AccessToken    sql.NullString `json:"access_token"`
-	RefreshToken   sql.NullString `json:"refresh_token"`
-	ExpiresAt_2    sql.NullTime   `json:"expires_at_2"`
-	Name           sql.NullString `json:"name"`
-	FirstName      sql.NullString `json:"first_name"`
-	LastName       sql.NullString `json:"last_name"`
-	NickName       sql.NullString `json:"nick_name"`
-	Description    sql.NullString `json:"description"`
-	Location       sql.NullString `json:"location"`
-	CreatedAt_2    sql.NullTime   `json:"created_at_2"`
-	UpdatedAt_2    sql.NullTime   `json:"updated_at_2"`
+	ID                  string         `json:"id"`
+	UserID              int32          `json:"user_id"`
+	Provider            sql.NullString `json:"provider"`
+	IpAddress           sql.NullString `json:"ip_address"`
+	UserAgent           sql.NullString `json:"user_agent"`
+	ExpiresAt           time.Time      `json:"expires_at"`
+	CreatedAt           sql.NullTime   `json:"created_at"`
+	UpdatedAt           sql.NullTime   `json:"updated_at"`
+	MfaPending          bool           `json:"mfa_pending"`
+	Kind                string         `json:"kind"`
+	TokenFamily         sql.NullString `json:"token_family"`
+	RotatedAt           sql.NullTime   `json:"rotated_at"`
+	Roles               string         `json:"roles"`
+	Permissions         string         `json:"permissions"`
+	ImpersonatorID      sql.NullInt32  `json:"impersonator_id"`
+	ID_2                int32          `json:"id_2"`
+	Username            string         `json:"username"`
+	Email               string         `json:"email"`
+	PasswordHash        sql.NullString `json:"password_hash"`
+	AvatarUrl           sql.NullString `json:"avatar_url"`
+	Provider_2          sql.NullString `json:"provider_2"`
+	ProviderUserID      sql.NullString `json:"provider_user_id"`
+	Name                sql.NullString `json:"name"`
+	FirstName           sql.NullString `json:"first_name"`
+	LastName            sql.NullString `json:"last_name"`
+	NickName            sql.NullString `json:"nick_name"`
+	Description         sql.NullString `json:"description"`
+	Location            sql.NullString `json:"location"`
+	CreatedAt_2         sql.NullTime   `json:"created_at_2"`
+	UpdatedAt_2         sql.NullTime   `json:"updated_at_2"`
+	EmailVerifiedAt     sql.NullTime   `json:"email_verified_at"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabledAt       sql.NullTime   `json:"totp_enabled_at"`
+	TotpLastStep        sql.NullInt64  `json:"totp_last_step"`
+	SuspendedAt         sql.NullTime   `json:"suspended_at"`
+	SuspendedUntil      sql.NullTime   `json:"suspended_until"`
+	SuspensionReason    sql.NullString `json:"suspension_reason"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
 }
 
 func (q *Queries) GetSessionByID(ctx context.Context, id string) (GetSessionByIDRow, error) {
@@ -131,6 +382,13 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (GetSessionByID
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MfaPending,
+		&i.Kind,
+		&i.TokenFamily,
+		&i.RotatedAt,
+		&i.Roles,
+		&i.Permissions,
+		&i.ImpersonatorID,
 		&i.ID_2,
 		&i.Username,
 		&i.Email,
@@ -138,9 +396,6 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (GetSessionByID
 		&i.AvatarUrl,
 		&i.Provider_2,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt_2,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -149,13 +404,22 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (GetSessionByID
 		&i.Location,
 		&i.CreatedAt_2,
 		&i.UpdatedAt_2,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
 const getUserSessions = `-- name: GetUserSessions :many
-SELECT id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at FROM sessions
-WHERE user_id = $1 AND expires_at > NOW()
+SELECT id, user_id, provider, ip_address, user_agent, expires_at, created_at, updated_at, mfa_pending, kind, token_family, rotated_at, roles, permissions, impersonator_id FROM sessions
+WHERE user_id = $1 AND expires_at > NOW() AND rotated_at IS NULL
+  AND impersonator_id IS NULL AND NOT mfa_pending
 ORDER BY created_at DESC
 `
 
@@ -177,6 +441,13 @@ func (q *Queries) GetUserSessions(ctx context.Context, userID int32) ([]Session,
 			&i.ExpiresAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MfaPending,
+			&i.Kind,
+			&i.TokenFamily,
+			&i.RotatedAt,
+			&i.Roles,
+			&i.Permissions,
+			&i.ImpersonatorID,
 		); err != nil {
 			return nil, err
 		}
@@ -190,3 +461,17 @@ func (q *Queries) GetUserSessions(ctx context.Context, userID int32) ([]Session,
 	}
 	return items, nil
 }
+
+const rotateRefreshSession = `-- name: RotateRefreshSession :execrows
+UPDATE sessions
+SET rotated_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND rotated_at IS NULL AND expires_at > NOW()
+`
+
+func (q *Queries) RotateRefreshSession(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshSession, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -10,6 +10,20 @@ import (
 	"database/sql"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createOAuthUser = `-- name: CreateOAuthUser :one
 INSERT INTO users (
     username,
@@ -17,35 +31,31 @@ INSERT INTO users (
     avatar_url,
     provider,
     provider_user_id,
-    access_token,
-    refresh_token,
-    expires_at,
     name,
     first_name,
     last_name,
     nick_name,
     description,
-    location
+    location,
+    email_verified_at
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at
 `
 
 type CreateOAuthUserParams struct {
-	Username       string         `json:"username"`
-	Email          string         `json:"email"`
-	AvatarUrl      sql.NullString `json:"avatar_url"`
-	Provider       sql.NullString `json:"provider"`
-	ProviderUserID sql.NullString `json:"provider_user_id"`
-	AccessToken    sql.NullString `json:"access_token"`
-	RefreshToken   sql.NullString `json:"refresh_token"`
-	ExpiresAt      sql.NullTime   `json:"expires_at"`
-	Name           sql.NullString `json:"name"`
-	FirstName      sql.NullString `json:"first_name"`
-	LastName       sql.NullString `json:"last_name"`
-	NickName       sql.NullString `json:"nick_name"`
-	Description    sql.NullString `json:"description"`
-	Location       sql.NullString `json:"location"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	AvatarUrl       sql.NullString `json:"avatar_url"`
+	Provider        sql.NullString `json:"provider"`
+	ProviderUserID  sql.NullString `json:"provider_user_id"`
+	Name            sql.NullString `json:"name"`
+	FirstName       sql.NullString `json:"first_name"`
+	LastName        sql.NullString `json:"last_name"`
+	NickName        sql.NullString `json:"nick_name"`
+	Description     sql.NullString `json:"description"`
+	Location        sql.NullString `json:"location"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
 
 func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (User, error) {
@@ -55,15 +65,13 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		arg.AvatarUrl,
 		arg.Provider,
 		arg.ProviderUserID,
-		arg.AccessToken,
-		arg.RefreshToken,
-		arg.ExpiresAt,
 		arg.Name,
 		arg.FirstName,
 		arg.LastName,
 		arg.NickName,
 		arg.Description,
 		arg.Location,
+		arg.EmailVerifiedAt,
 	)
 	var i User
 	err := row.Scan(
@@ -74,9 +82,6 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -85,10 +90,78 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
+	)
+	return i, err
+}
+
+const createPasswordUser = `-- name: CreatePasswordUser :one
+INSERT INTO users (
+    username,
+    email,
+    password_hash
+)
+VALUES ($1, $2, $3)
+    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at
+`
+
+type CreatePasswordUserParams struct {
+	Username     string         `json:"username"`
+	Email        string         `json:"email"`
+	PasswordHash sql.NullString `json:"password_hash"`
+}
+
+func (q *Queries) CreatePasswordUser(ctx context.Context, arg CreatePasswordUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordUser, arg.Username, arg.Email, arg.PasswordHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.AvatarUrl,
+		&i.Provider,
+		&i.ProviderUserID,
+		&i.Name,
+		&i.FirstName,
+		&i.LastName,
+		&i.NickName,
+		&i.Description,
+		&i.Location,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
+const deleteScheduledUser = `-- name: DeleteScheduledUser :execrows
+DELETE FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW()
+`
+
+func (q *Queries) DeleteScheduledUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1
@@ -100,7 +173,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
 WHERE email = $1
 `
 
@@ -115,9 +188,6 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -126,12 +196,20 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
 WHERE id = $1
 `
 
@@ -146,9 +224,6 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -157,12 +232,20 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
 const getUserByProviderID = `-- name: GetUserByProviderID :one
-SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
 WHERE provider = $1 AND provider_user_id = $2
 `
 
@@ -182,9 +265,6 @@ func (q *Queries) GetUserByProviderID(ctx context.Context, arg GetUserByProvider
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -193,23 +273,74 @@ func (q *Queries) GetUserByProviderID(ctx context.Context, arg GetUserByProvider
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
+	)
+	return i, err
+}
+
+const getUserSuspension = `-- name: GetUserSuspension :one
+SELECT suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
+WHERE id = $1
+`
+
+type GetUserSuspensionRow struct {
+	SuspendedAt         sql.NullTime   `json:"suspended_at"`
+	SuspendedUntil      sql.NullTime   `json:"suspended_until"`
+	SuspensionReason    sql.NullString `json:"suspension_reason"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
+}
+
+func (q *Queries) GetUserSuspension(ctx context.Context, id int32) (GetUserSuspensionRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserSuspension, id)
+	var i GetUserSuspensionRow
+	err := row.Scan(
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at FROM users
-ORDER BY created_at DESC
-    LIMIT $1 OFFSET $2
+SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
+WHERE ($1::text IS NULL OR email ILIKE $1)
+  AND ($2::text IS NULL OR username ILIKE $2)
+  AND ($3::text IS NULL
+    OR provider = $3
+    OR ($3::text = 'password' AND password_hash IS NOT NULL)
+    OR EXISTS (
+        SELECT 1 FROM user_identities
+        WHERE user_identities.user_id = users.id AND user_identities.provider = $3
+    ))
+  AND ($4::integer IS NULL OR id < $4)
+ORDER BY id DESC
+LIMIT $5
 `
 
 type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	EmailPattern    sql.NullString `json:"email_pattern"`
+	UsernamePattern sql.NullString `json:"username_pattern"`
+	Provider        sql.NullString `json:"provider"`
+	BeforeID        sql.NullInt32  `json:"before_id"`
+	RowLimit        int32          `json:"row_limit"`
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.EmailPattern,
+		arg.UsernamePattern,
+		arg.Provider,
+		arg.BeforeID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -225,9 +356,6 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.AvatarUrl,
 			&i.Provider,
 			&i.ProviderUserID,
-			&i.AccessToken,
-			&i.RefreshToken,
-			&i.ExpiresAt,
 			&i.Name,
 			&i.FirstName,
 			&i.LastName,
@@ -236,6 +364,14 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Location,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.SuspendedAt,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.DeletionScheduledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -250,21 +386,52 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
-const updateUser = `-- name: UpdateUser :one
-UPDATE users
-SET username = $2, avatar_url = $3, updated_at = NOW()
-WHERE id = $1
-    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at
+const listUsersDueForDeletion = `-- name: ListUsersDueForDeletion :many
+SELECT id, email FROM users
+WHERE deletion_scheduled_at <= NOW()
+ORDER BY deletion_scheduled_at
+LIMIT $1
 `
 
-type UpdateUserParams struct {
-	ID        int32          `json:"id"`
-	Username  string         `json:"username"`
-	AvatarUrl sql.NullString `json:"avatar_url"`
+type ListUsersDueForDeletionRow struct {
+	ID    int32  `json:"id"`
+	Email string `json:"email"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Username, arg.AvatarUrl)
+func (q *Queries) ListUsersDueForDeletion(ctx context.Context, limit int32) ([]ListUsersDueForDeletionRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersDueForDeletion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersDueForDeletionRow{}
+	for rows.Next() {
+		var i ListUsersDueForDeletionRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserByID = `-- name: LockUserByID :one
+SELECT id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUserByID(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, lockUserByID, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -274,9 +441,6 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -285,35 +449,105 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
-const updateUserOAuthTokens = `-- name: UpdateUserOAuthTokens :one
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :execrows
 UPDATE users
-SET
-    access_token = $2,
-    refresh_token = $3,
-    expires_at = $4,
-    updated_at = NOW()
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    int32  `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = $2, updated_at = NOW()
 WHERE id = $1
-    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, access_token, refresh_token, expires_at, name, first_name, last_name, nick_name, description, location, created_at, updated_at
 `
 
-type UpdateUserOAuthTokensParams struct {
-	ID           int32          `json:"id"`
-	AccessToken  sql.NullString `json:"access_token"`
-	RefreshToken sql.NullString `json:"refresh_token"`
-	ExpiresAt    sql.NullTime   `json:"expires_at"`
+type ScheduleUserDeletionParams struct {
+	ID                  int32        `json:"id"`
+	DeletionScheduledAt sql.NullTime `json:"deletion_scheduled_at"`
 }
 
-func (q *Queries) UpdateUserOAuthTokens(ctx context.Context, arg UpdateUserOAuthTokensParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUserOAuthTokens,
-		arg.ID,
-		arg.AccessToken,
-		arg.RefreshToken,
-		arg.ExpiresAt,
-	)
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, scheduleUserDeletion, arg.ID, arg.DeletionScheduledAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), suspended_until = $2, suspension_reason = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SuspendUserParams struct {
+	ID               int32          `json:"id"`
+	SuspendedUntil   sql.NullTime   `json:"suspended_until"`
+	SuspensionReason sql.NullString `json:"suspension_reason"`
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL, updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NOT NULL
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsuspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET username = $2, avatar_url = $3, updated_at = NOW()
+WHERE id = $1
+    RETURNING id, username, email, password_hash, avatar_url, provider, provider_user_id, name, first_name, last_name, nick_name, description, location, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, suspended_at, suspended_until, suspension_reason, deletion_scheduled_at
+`
+
+type UpdateUserParams struct {
+	ID        int32          `json:"id"`
+	Username  string         `json:"username"`
+	AvatarUrl sql.NullString `json:"avatar_url"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Username, arg.AvatarUrl)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -323,9 +557,6 @@ func (q *Queries) UpdateUserOAuthTokens(ctx context.Context, arg UpdateUserOAuth
 		&i.AvatarUrl,
 		&i.Provider,
 		&i.ProviderUserID,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.ExpiresAt,
 		&i.Name,
 		&i.FirstName,
 		&i.LastName,
@@ -334,6 +565,30 @@ func (q *Queries) UpdateUserOAuthTokens(ctx context.Context, arg UpdateUserOAuth
 		&i.Location,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           int32          `json:"id"`
+	PasswordHash sql.NullString `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := k.Encrypt("gho_secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ciphertext, "enc:v1:k1:") {
+		t.Fatalf("unexpected ciphertext format: %s", ciphertext)
+	}
+	if strings.Contains(ciphertext, "gho_secret") {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "gho_secret" {
+		t.Fatalf("expected gho_secret, got %s", plaintext)
+	}
+}
+
+func TestDecryptLegacyPlaintext(t *testing.T) {
+	k, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := k.Decrypt("legacy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "legacy-token" {
+		t.Fatalf("expected legacy-token, got %s", plaintext)
+	}
+	if !k.NeedsRotation("legacy-token") {
+		t.Fatal("expected plaintext value to need rotation")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := old.Encrypt("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := ParseKeyring("k2:" + testKey('b') + ",k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated.NeedsRotation(ciphertext) {
+		t.Fatal("expected value sealed with k1 to need rotation")
+	}
+
+	plaintext, err := rotated.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencrypted, err := rotated.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.NeedsRotation(reencrypted) {
+		t.Fatal("expected re-encrypted value to use the primary key")
+	}
+
+	retired, err := ParseKeyring("k2:" + testKey('b'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := retired.Decrypt(ciphertext); err == nil {
+		t.Fatal("expected decryption with a retired key to fail")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	k, err := ParseKeyring("k1:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := k.Encrypt("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := ciphertext[:len(ciphertext)-2] + "AA"
+	if tampered == ciphertext {
+		tampered = ciphertext[:len(ciphertext)-2] + "BB"
+	}
+	if _, err := k.Decrypt(tampered); err == nil {
+		t.Fatal("expected tampered ciphertext to fail")
+	}
+}
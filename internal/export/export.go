@@ -0,0 +1,320 @@
+// Package export builds personal data export archives: a ZIP of JSON files
+// with everything stored about a user. Archives are built by a background
+// job, kept in a storage.Storage and downloaded once through a signed link.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/config"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/mail"
+	"huddle-backend/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Export statuses.
+const (
+	StatusPending    = "pending"
+	StatusReady      = "ready"
+	StatusDownloaded = "downloaded"
+	StatusFailed     = "failed"
+)
+
+// buildBatch and purgeBatch cap how many exports one job run handles.
+const (
+	buildBatch = 10
+	purgeBatch = 100
+)
+
+const uniqueViolation = "23505"
+
+var (
+	ErrExportInProgress = errors.New("a data export is already being prepared")
+	ErrLinkInvalid      = errors.New("this download link is not valid")
+	ErrLinkUsed         = errors.New("this download link has expired or was used already")
+)
+
+type Service struct {
+	queries *sqlc.Queries
+	storage storage.Storage
+	signer  *storage.Signer
+	mail    *mail.Service
+	ttl     time.Duration
+	baseURL string
+}
+
+// NewService reads DATA_EXPORT_TTL (default 48h), how long a finished
+// archive can be downloaded, and API_URL, the public URL of this API that
+// download links point to.
+func NewService(queries *sqlc.Queries, store storage.Storage, signer *storage.Signer, mailService *mail.Service) *Service {
+	baseURL := os.Getenv("API_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + os.Getenv("PORT")
+	}
+	return &Service{
+		queries: queries,
+		storage: store,
+		signer:  signer,
+		mail:    mailService,
+		ttl:     config.Duration("DATA_EXPORT_TTL", 48*time.Hour),
+		baseURL: baseURL,
+	}
+}
+
+// Request queues an export of the user's data. A user has at most one
+// pending export.
+func (s *Service) Request(ctx context.Context, userID int32) (sqlc.DataExport, error) {
+	export, err := s.queries.CreateDataExport(ctx, sql.NullInt32{Int32: userID, Valid: true})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return sqlc.DataExport{}, ErrExportInProgress
+	}
+	if err != nil {
+		return sqlc.DataExport{}, fmt.Errorf("error creating data export: %w", err)
+	}
+	return export, nil
+}
+
+// List returns the user's exports, newest first.
+func (s *Service) List(ctx context.Context, userID int32) ([]sqlc.DataExport, error) {
+	exports, err := s.queries.ListUserDataExports(ctx, sql.NullInt32{Int32: userID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error listing data exports: %w", err)
+	}
+	return exports, nil
+}
+
+// DownloadURL returns the signed link of a ready export, or "" for exports
+// that cannot be downloaded.
+func (s *Service) DownloadURL(export sqlc.DataExport) string {
+	if export.Status != StatusReady || !export.ExpiresAt.Valid || !export.ExpiresAt.Time.After(time.Now()) {
+		return ""
+	}
+	query := s.signer.Sign(resource(export.ID), export.ExpiresAt.Time)
+	return s.baseURL + "/exports/" + strconv.FormatInt(export.ID, 10) + "?" + query.Encode()
+}
+
+// BuildPending builds the archives of pending exports and emails their
+// users the download link. It returns how many were built. An export that
+// fails is marked failed, and the user can ask for a new one.
+func (s *Service) BuildPending(ctx context.Context) (int64, error) {
+	pending, err := s.queries.ListPendingDataExports(ctx, buildBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing pending data exports: %w", err)
+	}
+
+	var built int64
+	for _, export := range pending {
+		ready, err := s.build(ctx, export)
+		if err != nil {
+			log.Printf("Data export %d failed: %v", export.ID, err)
+			if err := s.queries.FailDataExport(ctx, export.ID); err != nil {
+				return built, fmt.Errorf("error marking data export failed: %w", err)
+			}
+			continue
+		}
+		built++
+
+		if err := s.notify(ctx, ready); err != nil {
+			log.Printf("Failed to send data export %d email: %v", export.ID, err)
+		}
+	}
+	return built, nil
+}
+
+func (s *Service) build(ctx context.Context, export sqlc.DataExport) (sqlc.DataExport, error) {
+	userID := export.UserID.Int32
+	now := time.Now().UTC()
+
+	files := make([]archiveFile, 0, len(sections))
+	for _, section := range sections {
+		data, err := section.Collect(ctx, s.queries, userID)
+		if err != nil {
+			return sqlc.DataExport{}, fmt.Errorf("error collecting %s: %w", section.File, err)
+		}
+		files = append(files, archiveFile{Name: section.File, Data: data})
+	}
+
+	var archive bytes.Buffer
+	if err := writeArchive(&archive, userID, now, files); err != nil {
+		return sqlc.DataExport{}, err
+	}
+
+	key := fmt.Sprintf("exports/%d/%d.zip", userID, export.ID)
+	size := int64(archive.Len())
+	if err := s.storage.Put(ctx, key, &archive); err != nil {
+		return sqlc.DataExport{}, err
+	}
+
+	export.Status = StatusReady
+	export.StorageKey = sql.NullString{String: key, Valid: true}
+	export.SizeBytes = sql.NullInt64{Int64: size, Valid: true}
+	export.ExpiresAt = sql.NullTime{Time: now.Add(s.ttl), Valid: true}
+	completed, err := s.queries.CompleteDataExport(ctx, sqlc.CompleteDataExportParams{
+		ID:         export.ID,
+		StorageKey: export.StorageKey,
+		SizeBytes:  export.SizeBytes,
+		ExpiresAt:  export.ExpiresAt,
+	})
+	if err == nil && completed == 0 {
+		err = errors.New("data export is no longer pending")
+	}
+	if err != nil {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete data export archive %s: %v", key, err)
+		}
+		return sqlc.DataExport{}, fmt.Errorf("error completing data export: %w", err)
+	}
+	return export, nil
+}
+
+func (s *Service) notify(ctx context.Context, export sqlc.DataExport) error {
+	user, err := s.queries.GetUserByID(ctx, export.UserID.Int32)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	return s.mail.Send(ctx, user.Email, "data_export_ready", map[string]string{
+		"Username":    user.Username,
+		"DownloadURL": s.DownloadURL(export),
+		"ExpiresAt":   export.ExpiresAt.Time.UTC().Format("January 2, 2006 at 15:04 UTC"),
+	})
+}
+
+// Open checks a download link and hands out the archive. The archive is
+// opened before the export is claimed, so a missing archive does not use up
+// the link. From here on the export counts as downloaded and the link works
+// only once: call Discard once the whole archive was sent, or Release if the
+// download broke off.
+func (s *Service) Open(ctx context.Context, id int64, query url.Values) (io.ReadCloser, sqlc.DataExport, error) {
+	switch err := s.signer.Verify(resource(id), query, time.Now()); {
+	case errors.Is(err, storage.ErrLinkExpired):
+		return nil, sqlc.DataExport{}, ErrLinkUsed
+	case err != nil:
+		return nil, sqlc.DataExport{}, ErrLinkInvalid
+	}
+
+	export, err := s.queries.GetDataExport(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, sqlc.DataExport{}, ErrLinkUsed
+	}
+	if err != nil {
+		return nil, sqlc.DataExport{}, fmt.Errorf("error getting data export: %w", err)
+	}
+	if export.Status != StatusReady || !export.StorageKey.Valid {
+		return nil, sqlc.DataExport{}, ErrLinkUsed
+	}
+
+	archive, err := s.storage.Open(ctx, export.StorageKey.String)
+	if err != nil {
+		return nil, sqlc.DataExport{}, fmt.Errorf("error opening data export archive: %w", err)
+	}
+
+	export, err = s.queries.ClaimDataExportDownload(ctx, id)
+	if err != nil {
+		archive.Close()
+		if err == sql.ErrNoRows {
+			return nil, sqlc.DataExport{}, ErrLinkUsed
+		}
+		return nil, sqlc.DataExport{}, fmt.Errorf("error claiming data export: %w", err)
+	}
+	return archive, export, nil
+}
+
+// Release makes an export downloadable again after its download broke off,
+// unless its archive was purged meanwhile.
+func (s *Service) Release(ctx context.Context, export sqlc.DataExport) error {
+	if err := s.queries.ReleaseDataExportDownload(ctx, export.ID); err != nil {
+		return fmt.Errorf("error releasing data export: %w", err)
+	}
+	return nil
+}
+
+// Discard removes the archive of an export.
+func (s *Service) Discard(ctx context.Context, export sqlc.DataExport) error {
+	if export.StorageKey.Valid {
+		if err := s.storage.Delete(ctx, export.StorageKey.String); err != nil {
+			return err
+		}
+	}
+	if !export.UserID.Valid {
+		return s.queries.DeleteDataExport(ctx, export.ID)
+	}
+	return s.queries.ClearDataExportStorage(ctx, export.ID)
+}
+
+// Purge removes the archives of exports that were downloaded or expired,
+// and drops the exports of deleted users altogether. It returns how many
+// exports it cleaned up.
+func (s *Service) Purge(ctx context.Context) (int64, error) {
+	exports, err := s.queries.ListDataExportsToPurge(ctx, purgeBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing data exports to purge: %w", err)
+	}
+
+	var purged int64
+	for _, export := range exports {
+		if err := s.Discard(ctx, export); err != nil {
+			return purged, fmt.Errorf("error purging data export %d: %w", export.ID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// resource names an export in its signed download link.
+func resource(id int64) string {
+	return "data_export:" + strconv.FormatInt(id, 10)
+}
+
+type archiveFile struct {
+	Name string
+	Data any
+}
+
+type manifest struct {
+	UserID      int32     `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []string  `json:"files"`
+}
+
+// writeArchive writes files as indented JSON into a ZIP, along with a
+// manifest.json listing them.
+func writeArchive(w io.Writer, userID int32, now time.Time, files []archiveFile) error {
+	index := manifest{UserID: userID, GeneratedAt: now}
+	for _, file := range files {
+		index.Files = append(index.Files, file.Name)
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range append([]archiveFile{{Name: "manifest.json", Data: index}}, files...) {
+		data, err := json.MarshalIndent(file.Data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding %s: %w", file.Name, err)
+		}
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return fmt.Errorf("error adding %s: %w", file.Name, err)
+		}
+		if _, err := entry.Write(data); err != nil {
+			return fmt.Errorf("error writing %s: %w", file.Name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	return nil
+}
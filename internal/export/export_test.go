@@ -0,0 +1,99 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var tableStatement = regexp.MustCompile(`(?i)^\s*(?:CREATE TABLE(?: IF NOT EXISTS)?|ALTER TABLE)\s+(\w+)`)
+
+// TestSectionsCoverUserTables makes sure every table that references users
+// ends up in the archive.
+func TestSectionsCoverUserTables(t *testing.T) {
+	migrations, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+
+	covered := map[string]bool{}
+	for _, section := range sections {
+		for _, table := range section.Tables {
+			covered[table] = true
+		}
+	}
+
+	for _, migration := range migrations {
+		data, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				lines = append(lines, line)
+			}
+		}
+		for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+			if !strings.Contains(statement, "REFERENCES users(") {
+				continue
+			}
+			match := tableStatement.FindStringSubmatch(statement)
+			if match == nil {
+				t.Errorf("%s: cannot tell which table references users in %q", filepath.Base(migration), strings.TrimSpace(statement))
+				continue
+			}
+			if !covered[match[1]] {
+				t.Errorf("%s: table %s references users but no export section covers it", filepath.Base(migration), match[1])
+			}
+		}
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := writeArchive(&buf, 42, now, []archiveFile{
+		{Name: "user.json", Data: userRecord{ID: 42, Username: "ada"}},
+		{Name: "profile.json", Data: nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		contents[file.Name] = string(data)
+	}
+
+	var index manifest
+	if err := json.Unmarshal([]byte(contents["manifest.json"]), &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.UserID != 42 || !index.GeneratedAt.Equal(now) || strings.Join(index.Files, ",") != "user.json,profile.json" {
+		t.Fatalf("unexpected manifest %+v", index)
+	}
+	if !strings.Contains(contents["user.json"], `"username": "ada"`) || strings.Contains(contents["user.json"], "password_hash") {
+		t.Fatalf("unexpected user.json %s", contents["user.json"])
+	}
+	if contents["profile.json"] != "null" {
+		t.Fatalf("expected a missing profile to be null, got %s", contents["profile.json"])
+	}
+}
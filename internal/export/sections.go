@@ -0,0 +1,372 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// A Section is one JSON file of the archive. Tables names the tables it
+// covers: a migration that adds a table referencing users needs a section
+// here, or TestSectionsCoverUserTables fails. Sections copy fields one by
+// one, so secrets such as password hashes, TOTP secrets, OAuth tokens and
+// token hashes never end up in an archive.
+type Section struct {
+	File    string
+	Tables  []string
+	Collect func(ctx context.Context, q *sqlc.Queries, userID int32) (any, error)
+}
+
+var sections = []Section{
+	{File: "user.json", Tables: []string{"users"}, Collect: collectUser},
+	{File: "profile.json", Tables: []string{"profiles"}, Collect: collectProfile},
+	{File: "roles.json", Tables: []string{"user_roles"}, Collect: collectRoles},
+	{File: "identities.json", Tables: []string{"user_identities"}, Collect: collectIdentities},
+	{File: "sessions.json", Tables: []string{"sessions"}, Collect: collectSessions},
+	{File: "access_tokens.json", Tables: []string{"personal_access_tokens"}, Collect: collectAccessTokens},
+	{File: "auth_events.json", Tables: []string{"auth_events"}, Collect: collectAuthEvents},
+	{File: "password_resets.json", Tables: []string{"password_reset_tokens"}, Collect: collectPasswordResets},
+	{File: "email_verifications.json", Tables: []string{"email_verification_tokens"}, Collect: collectEmailVerifications},
+	{File: "recovery_codes.json", Tables: []string{"two_factor_recovery_codes"}, Collect: collectRecoveryCodes},
+	{File: "admin_actions.json", Tables: []string{"admin_actions"}, Collect: collectAdminActions},
+	{File: "data_exports.json", Tables: []string{"data_exports"}, Collect: collectDataExports},
+}
+
+type userRecord struct {
+	ID                  int32      `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Name                *string    `json:"name"`
+	FirstName           *string    `json:"first_name"`
+	LastName            *string    `json:"last_name"`
+	NickName            *string    `json:"nick_name"`
+	Description         *string    `json:"description"`
+	Location            *string    `json:"location"`
+	AvatarURL           *string    `json:"avatar_url"`
+	Provider            *string    `json:"provider"`
+	ProviderUserID      *string    `json:"provider_user_id"`
+	HasPassword         bool       `json:"has_password"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	TwoFactorEnabledAt  *time.Time `json:"two_factor_enabled_at"`
+	SuspendedAt         *time.Time `json:"suspended_at"`
+	SuspendedUntil      *time.Time `json:"suspended_until"`
+	SuspensionReason    *string    `json:"suspension_reason"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           *time.Time `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at"`
+}
+
+func collectUser(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return userRecord{
+		ID:                  user.ID,
+		Username:            user.Username,
+		Email:               user.Email,
+		Name:                text(user.Name),
+		FirstName:           text(user.FirstName),
+		LastName:            text(user.LastName),
+		NickName:            text(user.NickName),
+		Description:         text(user.Description),
+		Location:            text(user.Location),
+		AvatarURL:           text(user.AvatarUrl),
+		Provider:            text(user.Provider),
+		ProviderUserID:      text(user.ProviderUserID),
+		HasPassword:         user.PasswordHash.Valid,
+		EmailVerifiedAt:     timestamp(user.EmailVerifiedAt),
+		TwoFactorEnabledAt:  timestamp(user.TotpEnabledAt),
+		SuspendedAt:         timestamp(user.SuspendedAt),
+		SuspendedUntil:      timestamp(user.SuspendedUntil),
+		SuspensionReason:    text(user.SuspensionReason),
+		DeletionScheduledAt: timestamp(user.DeletionScheduledAt),
+		CreatedAt:           timestamp(user.CreatedAt),
+		UpdatedAt:           timestamp(user.UpdatedAt),
+	}, nil
+}
+
+type profileRecord struct {
+	Username    string     `json:"username"`
+	DisplayName *string    `json:"display_name"`
+	Bio         *string    `json:"bio"`
+	Website     *string    `json:"website"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// collectProfile returns null for users without a profile.
+func collectProfile(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	profile, err := q.GetProfileByUserID(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return profileRecord{
+		Username:    profile.Username,
+		DisplayName: text(profile.DisplayName),
+		Bio:         text(profile.Bio),
+		Website:     text(profile.Website),
+		CreatedAt:   timestamp(profile.CreatedAt),
+		UpdatedAt:   timestamp(profile.UpdatedAt),
+	}, nil
+}
+
+type roleRecord struct {
+	Role      string     `json:"role"`
+	GrantedAt *time.Time `json:"granted_at"`
+}
+
+func collectRoles(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	grants, err := q.ListUserRoleGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]roleRecord, len(grants))
+	for i, grant := range grants {
+		records[i] = roleRecord{Role: grant.Name, GrantedAt: timestamp(grant.CreatedAt)}
+	}
+	return records, nil
+}
+
+type identityRecord struct {
+	Provider       string     `json:"provider"`
+	ProviderUserID string     `json:"provider_user_id"`
+	Email          *string    `json:"email"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+func collectIdentities(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	identities, err := q.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]identityRecord, len(identities))
+	for i, identity := range identities {
+		records[i] = identityRecord{
+			Provider:       identity.Provider,
+			ProviderUserID: identity.ProviderUserID,
+			Email:          text(identity.Email),
+			CreatedAt:      timestamp(identity.CreatedAt),
+			UpdatedAt:      timestamp(identity.UpdatedAt),
+		}
+	}
+	return records, nil
+}
+
+type sessionRecord struct {
+	Kind         string     `json:"kind"`
+	Provider     *string    `json:"provider"`
+	IPAddress    *string    `json:"ip_address"`
+	UserAgent    *string    `json:"user_agent"`
+	Impersonated bool       `json:"impersonated"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+}
+
+func collectSessions(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	sessions, err := q.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]sessionRecord, len(sessions))
+	for i, session := range sessions {
+		records[i] = sessionRecord{
+			Kind:         session.Kind,
+			Provider:     text(session.Provider),
+			IPAddress:    text(session.IpAddress),
+			UserAgent:    text(session.UserAgent),
+			Impersonated: session.ImpersonatorID.Valid,
+			CreatedAt:    timestamp(session.CreatedAt),
+			UpdatedAt:    timestamp(session.UpdatedAt),
+			ExpiresAt:    session.ExpiresAt,
+		}
+	}
+	return records, nil
+}
+
+type accessTokenRecord struct {
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      string     `json:"scopes"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+func collectAccessTokens(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	tokens, err := q.ListUserPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]accessTokenRecord, len(tokens))
+	for i, token := range tokens {
+		records[i] = accessTokenRecord{
+			Name:        token.Name,
+			TokenPrefix: token.TokenPrefix,
+			Scopes:      token.Scopes,
+			LastUsedAt:  timestamp(token.LastUsedAt),
+			ExpiresAt:   timestamp(token.ExpiresAt),
+			CreatedAt:   timestamp(token.CreatedAt),
+		}
+	}
+	return records, nil
+}
+
+type authEventRecord struct {
+	Type      string    `json:"type"`
+	Provider  *string   `json:"provider"`
+	Email     *string   `json:"email"`
+	IPAddress *string   `json:"ip_address"`
+	UserAgent *string   `json:"user_agent"`
+	Detail    *string   `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func collectAuthEvents(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	events, err := q.ListUserAuthEvents(ctx, sql.NullInt32{Int32: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]authEventRecord, len(events))
+	for i, event := range events {
+		records[i] = authEventRecord{
+			Type:      event.EventType,
+			Provider:  text(event.Provider),
+			Email:     text(event.Email),
+			IPAddress: text(event.IpAddress),
+			UserAgent: text(event.UserAgent),
+			Detail:    text(event.Detail),
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return records, nil
+}
+
+// tokenRecord describes a single-use emailed token without the token.
+type tokenRecord struct {
+	Email     *string    `json:"email,omitempty"`
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func collectPasswordResets(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	tokens, err := q.ListUserPasswordResetTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]tokenRecord, len(tokens))
+	for i, token := range tokens {
+		records[i] = tokenRecord{
+			CreatedAt: timestamp(token.CreatedAt),
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    timestamp(token.UsedAt),
+		}
+	}
+	return records, nil
+}
+
+func collectEmailVerifications(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	tokens, err := q.ListUserEmailVerificationTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]tokenRecord, len(tokens))
+	for i, token := range tokens {
+		records[i] = tokenRecord{
+			Email:     &token.Email,
+			CreatedAt: timestamp(token.CreatedAt),
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    timestamp(token.UsedAt),
+		}
+	}
+	return records, nil
+}
+
+type recoveryCodeRecord struct {
+	CreatedAt *time.Time `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func collectRecoveryCodes(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	codes, err := q.ListUserRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]recoveryCodeRecord, len(codes))
+	for i, code := range codes {
+		records[i] = recoveryCodeRecord{CreatedAt: timestamp(code.CreatedAt), UsedAt: timestamp(code.UsedAt)}
+	}
+	return records, nil
+}
+
+// adminActionRecord is an admin action taken on the user. The acting admin
+// is left out.
+type adminActionRecord struct {
+	Action    string    `json:"action"`
+	Detail    *string   `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func collectAdminActions(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	actions, err := q.ListUserAdminActions(ctx, sql.NullInt32{Int32: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]adminActionRecord, len(actions))
+	for i, action := range actions {
+		records[i] = adminActionRecord{
+			Action:    action.Action,
+			Detail:    text(action.Detail),
+			CreatedAt: action.CreatedAt,
+		}
+	}
+	return records, nil
+}
+
+type dataExportRecord struct {
+	ID           int64      `json:"id"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	DownloadedAt *time.Time `json:"downloaded_at"`
+}
+
+func collectDataExports(ctx context.Context, q *sqlc.Queries, userID int32) (any, error) {
+	exports, err := q.ListUserDataExports(ctx, sql.NullInt32{Int32: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]dataExportRecord, len(exports))
+	for i, export := range exports {
+		records[i] = dataExportRecord{
+			ID:           export.ID,
+			Status:       export.Status,
+			CreatedAt:    export.CreatedAt,
+			CompletedAt:  timestamp(export.CompletedAt),
+			DownloadedAt: timestamp(export.DownloadedAt),
+		}
+	}
+	return records, nil
+}
+
+func text(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func timestamp(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
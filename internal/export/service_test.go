@@ -0,0 +1,261 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/mail"
+	"huddle-backend/internal/storage"
+)
+
+type testService struct {
+	*Service
+	store  *storage.MemoryStorage
+	mailer *mail.MemoryMailer
+}
+
+func newTestService(t *testing.T) testService {
+	t.Helper()
+	_, queries := dbtest.New(t)
+
+	signer, err := storage.NewSigner([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storage.NewMemoryStorage()
+	mailer := mail.NewMemoryMailer()
+	mailService, err := mail.NewService(mailer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(queries, store, signer, mailService)
+	service.baseURL = "https://api.example.com"
+	return testService{Service: service, store: store, mailer: mailer}
+}
+
+func (s testService) createUser(t *testing.T, username string) sqlc.User {
+	t.Helper()
+	user, err := s.queries.CreatePasswordUser(context.Background(), sqlc.CreatePasswordUserParams{
+		Username: username,
+		Email:    username + "@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// buildExport requests an export for user and builds it.
+func (s testService) buildExport(t *testing.T, user sqlc.User) sqlc.DataExport {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.Request(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if built, err := s.BuildPending(ctx); err != nil || built != 1 {
+		t.Fatalf("BuildPending built %d: %v", built, err)
+	}
+	exports, err := s.List(ctx, user.ID)
+	if err != nil || len(exports) != 1 {
+		t.Fatalf("List returned %d exports: %v", len(exports), err)
+	}
+	return exports[0]
+}
+
+func (s testService) linkQuery(t *testing.T, dataExport sqlc.DataExport) url.Values {
+	t.Helper()
+	link, err := url.Parse(s.DownloadURL(dataExport))
+	if err != nil || link.Path == "" {
+		t.Fatalf("no download link for export %d: %v", dataExport.ID, err)
+	}
+	return link.Query()
+}
+
+func TestRequestRejectsSecondPendingExport(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	user := s.createUser(t, "ada")
+
+	if _, err := s.Request(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Request(ctx, user.ID); !errors.Is(err, ErrExportInProgress) {
+		t.Fatalf("expected ErrExportInProgress, got %v", err)
+	}
+
+	// Another user is not held up.
+	other := s.createUser(t, "grace")
+	if _, err := s.Request(ctx, other.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildPending(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	user := s.createUser(t, "ada")
+
+	dataExport := s.buildExport(t, user)
+	if dataExport.Status != StatusReady || !dataExport.StorageKey.Valid || !dataExport.ExpiresAt.Valid {
+		t.Fatalf("unexpected export %+v", dataExport)
+	}
+
+	archive, err := s.store.Open(ctx, dataExport.StorageKey.String)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(archive)
+	archive.Close()
+	if int64(len(data)) != dataExport.SizeBytes.Int64 {
+		t.Fatalf("archive is %d bytes, export says %d", len(data), dataExport.SizeBytes.Int64)
+	}
+	files, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files.File[0].Name != "manifest.json" || len(files.File) != len(sections)+1 {
+		t.Fatalf("unexpected archive with %d files", len(files.File))
+	}
+
+	messages := s.mailer.Messages()
+	if len(messages) != 1 || messages[0].To != user.Email || !strings.Contains(messages[0].Text, s.DownloadURL(dataExport)) {
+		t.Fatalf("expected the download link to be emailed, got %+v", messages)
+	}
+
+	// Nothing is left to build.
+	if built, err := s.BuildPending(ctx); err != nil || built != 0 {
+		t.Fatalf("second BuildPending built %d: %v", built, err)
+	}
+}
+
+func TestOpenWorksOnce(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	dataExport := s.buildExport(t, s.createUser(t, "ada"))
+	query := s.linkQuery(t, dataExport)
+
+	archive, claimed, err := s.Open(ctx, dataExport.ID, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+	if claimed.Status != StatusDownloaded || !claimed.DownloadedAt.Valid {
+		t.Fatalf("expected the export to be claimed, got %+v", claimed)
+	}
+
+	if _, _, err := s.Open(ctx, dataExport.ID, query); !errors.Is(err, ErrLinkUsed) {
+		t.Fatalf("expected the second download to get ErrLinkUsed, got %v", err)
+	}
+
+	// A download that broke off hands the link back.
+	if err := s.Release(ctx, claimed); err != nil {
+		t.Fatal(err)
+	}
+	archive, claimed, err = s.Open(ctx, dataExport.ID, query)
+	if err != nil {
+		t.Fatalf("expected a released export to open again, got %v", err)
+	}
+	archive.Close()
+
+	if err := s.Discard(ctx, claimed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.store.Open(ctx, dataExport.StorageKey.String); err != storage.ErrNotFound {
+		t.Fatalf("expected the archive to be discarded, got %v", err)
+	}
+	if err := s.Release(ctx, claimed); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Open(ctx, dataExport.ID, query); !errors.Is(err, ErrLinkUsed) {
+		t.Fatalf("expected a discarded export to stay used, got %v", err)
+	}
+}
+
+func TestOpenRejectsBadLinks(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	dataExport := s.buildExport(t, s.createUser(t, "ada"))
+
+	expired := s.signer.Sign(resource(dataExport.ID), time.Now().Add(-time.Minute))
+	if _, _, err := s.Open(ctx, dataExport.ID, expired); !errors.Is(err, ErrLinkUsed) {
+		t.Fatalf("expected an expired link to get ErrLinkUsed, got %v", err)
+	}
+
+	if _, _, err := s.Open(ctx, dataExport.ID+1, s.linkQuery(t, dataExport)); !errors.Is(err, ErrLinkInvalid) {
+		t.Fatalf("expected a link for another export to get ErrLinkInvalid, got %v", err)
+	}
+
+	// Neither used the link up.
+	archive, _, err := s.Open(ctx, dataExport.ID, s.linkQuery(t, dataExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+}
+
+func TestOpenMissingArchiveKeepsLink(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	dataExport := s.buildExport(t, s.createUser(t, "ada"))
+	query := s.linkQuery(t, dataExport)
+
+	key := dataExport.StorageKey.String
+	archive, _ := s.store.Open(ctx, key)
+	data, _ := io.ReadAll(archive)
+	archive.Close()
+	if err := s.store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := s.Open(ctx, dataExport.ID, query)
+	if err == nil || errors.Is(err, ErrLinkUsed) || errors.Is(err, ErrLinkInvalid) {
+		t.Fatalf("expected a storage error, got %v", err)
+	}
+
+	if err := s.store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	archive, _, err = s.Open(ctx, dataExport.ID, query)
+	if err != nil {
+		t.Fatalf("expected the link to still work, got %v", err)
+	}
+	archive.Close()
+}
+
+func TestPurgeDropsExportsOfDeletedUsers(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	user := s.createUser(t, "ada")
+	dataExport := s.buildExport(t, user)
+
+	kept := s.buildExport(t, s.createUser(t, "grace"))
+
+	if err := s.queries.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := s.Purge(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("Purge purged %d: %v", purged, err)
+	}
+
+	if _, err := s.store.Open(ctx, dataExport.StorageKey.String); err != storage.ErrNotFound {
+		t.Fatalf("expected the archive to be removed, got %v", err)
+	}
+	if _, err := s.queries.GetDataExport(ctx, dataExport.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected the export to be deleted, got %v", err)
+	}
+	if _, err := s.store.Open(ctx, kept.StorageKey.String); err != nil {
+		t.Fatalf("expected another user's archive to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+	"huddle-backend/internal/profiles"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUserPage        = 50
+	defaultAdminActionPage = 50
+)
+
+// AdminHandler serves the /api/admin group. Every route is behind
+// RequirePermission, so handlers only check what is specific to them. Every
+// change is recorded in the admin audit trail.
+type AdminHandler struct {
+	authService    *auth.Service
+	profileService *profile.Service
+}
+
+func NewAdminHandler(authService *auth.Service, profileService *profile.Service) *AdminHandler {
+	return &AdminHandler{
+		authService:    authService,
+		profileService: profileService,
+	}
+}
+
+type adminUserResponse struct {
+	ID                  int32      `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Name                string     `json:"name,omitempty"`
+	AvatarURL           string     `json:"avatar_url,omitempty"`
+	Provider            string     `json:"provider,omitempty"`
+	HasPassword         bool       `json:"has_password"`
+	EmailVerified       bool       `json:"email_verified"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	Suspended           bool       `json:"suspended"`
+	SuspendedAt         *time.Time `json:"suspended_at"`
+	SuspendedUntil      *time.Time `json:"suspended_until"`
+	SuspensionReason    string     `json:"suspension_reason,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           *time.Time `json:"created_at"`
+}
+
+// newAdminUserResponse leaves out the password hash and TOTP secret.
+func newAdminUserResponse(user sqlc.User) adminUserResponse {
+	response := adminUserResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Name:             user.Name.String,
+		AvatarURL:        user.AvatarUrl.String,
+		Provider:         user.Provider.String,
+		HasPassword:      user.PasswordHash.Valid,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+		Suspended:        auth.Suspended(user.SuspendedAt, user.SuspendedUntil, time.Now()),
+		SuspensionReason: user.SuspensionReason.String,
+	}
+	if user.SuspendedAt.Valid {
+		response.SuspendedAt = &user.SuspendedAt.Time
+	}
+	if user.SuspendedUntil.Valid {
+		response.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	if user.DeletionScheduledAt.Valid {
+		response.DeletionScheduledAt = &user.DeletionScheduledAt.Time
+	}
+	if user.CreatedAt.Valid {
+		response.CreatedAt = &user.CreatedAt.Time
+	}
+	return response
+}
+
+type adminActionResponse struct {
+	ID           int64     `json:"id"`
+	Action       string    `json:"action"`
+	AdminID      *int32    `json:"admin_id"`
+	TargetUserID *int32    `json:"target_user_id"`
+	TargetEmail  string    `json:"target_email,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	IPAddress    string    `json:"ip_address"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ListUsers searches users by ?email=, ?username= (both substrings) and
+// ?provider=, newest first. Pass the returned next_before as ?before= to get
+// the next page.
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	before, limit, ok := pageQuery(c, defaultUserPage, auth.MaxUserPage)
+	if !ok {
+		return
+	}
+
+	filter := auth.UserFilter{
+		Email:    c.Query("email"),
+		Username: c.Query("username"),
+		Provider: c.Query("provider"),
+		BeforeID: int32(before),
+		Limit:    limit,
+	}
+	users, err := h.authService.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+		return
+	}
+
+	response := make([]adminUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newAdminUserResponse(user))
+	}
+
+	var next *int32
+	if len(users) > 0 && len(users) == int(limit) {
+		next = &users[len(users)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       response,
+		"next_before": next,
+	})
+}
+
+// GetUser returns a user with their roles, logins, sessions and profile.
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	user, err := h.authService.GetUser(ctx, userID)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		return
+	}
+
+	grants, err := h.authService.UserGrants(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get roles"})
+		return
+	}
+	identities, err := h.authService.ListIdentities(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list identities"})
+		return
+	}
+	sessions, err := h.authService.ListUserSessions(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	// Not every user has created a profile.
+	var userProfile *sqlc.Profile
+	if p, err := h.profileService.GetProfileByUserID(ctx, userID); err == nil {
+		userProfile = &p
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":        newAdminUserResponse(user),
+		"roles":       grants.Roles,
+		"permissions": grants.Permissions,
+		"identities":  newIdentityResponses(identities),
+		"sessions":    newSessionResponses(sessions, ""),
+		"profile":     userProfile,
+	})
+}
+
+// ForceLogout ends every session and refresh token of a user.
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.GetUser(c.Request.Context(), userID)
+	if !h.userChanged(c, err, "failed to log out user") {
+		return
+	}
+	if err := h.authService.DeleteUserSessions(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out user"})
+		return
+	}
+
+	action := middleware.AdminAction(c, auth.ActionForceLogout, userID)
+	action.TargetEmail = user.Email
+	h.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "user logged out successfully"})
+}
+
+// SuspendUser suspends a user with {"reason": "...", "until": "<RFC 3339>"},
+// or permanently when until is left out. It ends their sessions right away.
+// Suspending a suspended user replaces the suspension.
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	userID, ok := h.otherUserParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string     `json:"reason" binding:"required,max=500"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	err := h.authService.SuspendUser(c.Request.Context(), userID, req.Until, req.Reason)
+	if errors.Is(err, auth.ErrInvalidSuspension) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.userChanged(c, err, "failed to suspend user") {
+		return
+	}
+
+	action := middleware.AdminAction(c, auth.ActionSuspend, userID)
+	action.Detail = req.Reason
+	if req.Until != nil {
+		action.Detail += " (until " + req.Until.UTC().Format(time.RFC3339) + ")"
+	} else {
+		action.Detail += " (permanent)"
+	}
+	h.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "user suspended successfully"})
+}
+
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.authService.UnsuspendUser(c.Request.Context(), userID)
+	if errors.Is(err, auth.ErrNotSuspended) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.userChanged(c, err, "failed to unsuspend user") {
+		return
+	}
+
+	h.authService.RecordAdminAction(c.Request.Context(), middleware.AdminAction(c, auth.ActionUnsuspend, userID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "user unsuspended successfully"})
+}
+
+// DeleteUser removes a user and everything they own.
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, ok := h.otherUserParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.DeleteUser(c.Request.Context(), userID)
+	if !h.userChanged(c, err, "failed to delete user") {
+		return
+	}
+
+	// The trail keeps the email, since target_user_id is cleared with the
+	// user.
+	action := middleware.AdminAction(c, auth.ActionDeleteUser, userID)
+	action.TargetEmail = user.Email
+	h.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+// ListActions reads the admin audit trail, newest first, filtered by
+// ?admin_id= and ?user_id= (the target).
+func (h *AdminHandler) ListActions(c *gin.Context) {
+	before, limit, ok := pageQuery(c, defaultAdminActionPage, auth.MaxAdminActionPage)
+	if !ok {
+		return
+	}
+	filter := auth.AdminActionFilter{BeforeID: before, Limit: limit}
+	if filter.AdminID, ok = queryUserID(c, "admin_id"); !ok {
+		return
+	}
+	if filter.TargetUserID, ok = queryUserID(c, "user_id"); !ok {
+		return
+	}
+
+	actions, err := h.authService.ListAdminActions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list admin actions"})
+		return
+	}
+
+	response := make([]adminActionResponse, 0, len(actions))
+	for _, action := range actions {
+		item := adminActionResponse{
+			ID:          action.ID,
+			Action:      action.Action,
+			TargetEmail: action.TargetEmail.String,
+			Detail:      action.Detail.String,
+			IPAddress:   action.IpAddress.String,
+			UserAgent:   action.UserAgent.String,
+			CreatedAt:   action.CreatedAt,
+		}
+		if action.AdminID.Valid {
+			item.AdminID = &action.AdminID.Int32
+		}
+		if action.TargetUserID.Valid {
+			item.TargetUserID = &action.TargetUserID.Int32
+		}
+		response = append(response, item)
+	}
+
+	var next *int64
+	if len(actions) > 0 && len(actions) == int(limit) {
+		next = &actions[len(actions)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"actions":     response,
+		"next_before": next,
+	})
+}
+
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.authService.ListRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *AdminHandler) GrantRole(c *gin.Context) {
+	adminID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	err := h.authService.GrantRole(c.Request.Context(), userID, req.Role, adminID.(int32))
+	if !h.roleChanged(c, err, "failed to grant role") {
+		return
+	}
+
+	action := middleware.AdminAction(c, auth.ActionGrantRole, userID)
+	action.Detail = req.Role
+	h.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "role granted successfully"})
+}
+
+func (h *AdminHandler) RevokeRole(c *gin.Context) {
+	adminID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	role := c.Param("role")
+	// Admins cannot demote themselves, so that there is always one left.
+	if userID == adminID.(int32) && role == auth.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot revoke your own admin role"})
+		return
+	}
+
+	err := h.authService.RevokeRole(c.Request.Context(), userID, role)
+	if !h.roleChanged(c, err, "failed to revoke role") {
+		return
+	}
+
+	action := middleware.AdminAction(c, auth.ActionRevokeRole, userID)
+	action.Detail = role
+	h.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "role revoked successfully"})
+}
+
+// roleChanged answers the errors of GrantRole and RevokeRole. It reports
+// whether the change went through.
+func (h *AdminHandler) roleChanged(c *gin.Context, err error, failure string) bool {
+	if errors.Is(err, auth.ErrRoleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return false
+	}
+	return h.userChanged(c, err, failure)
+}
+
+// userChanged answers the errors of an action on a user. It reports whether
+// the action went through.
+func (h *AdminHandler) userChanged(c *gin.Context, err error, failure string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, auth.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	default:
+		log.Printf("Admin action error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+	return false
+}
+
+// otherUserParam reads the :id of a user the admin may lock out, which
+// excludes the admin's own account.
+func (h *AdminHandler) otherUserParam(c *gin.Context) (int32, bool) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return 0, false
+	}
+	if adminID, _ := c.Get(middleware.UserIDKey); adminID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot do this to your own account"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func userIDParam(c *gin.Context) (int32, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return int32(id), true
+}
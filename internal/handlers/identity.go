@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IdentityHandler struct {
+	authService *auth.Service
+}
+
+func NewIdentityHandler(authService *auth.Service) *IdentityHandler {
+	return &IdentityHandler{
+		authService: authService,
+	}
+}
+
+type identityResponse struct {
+	ID        int32      `json:"id"`
+	Provider  string     `json:"provider"`
+	Email     string     `json:"email"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+func (h *IdentityHandler) ListIdentities(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	identities, err := h.authService.ListIdentities(c.Request.Context(), userID.(int32))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": newIdentityResponses(identities)})
+}
+
+func newIdentityResponses(identities []sqlc.UserIdentity) []identityResponse {
+	response := make([]identityResponse, 0, len(identities))
+	for _, identity := range identities {
+		item := identityResponse{
+			ID:       identity.ID,
+			Provider: identity.Provider,
+			Email:    identity.Email.String,
+		}
+		if identity.CreatedAt.Valid {
+			item.CreatedAt = &identity.CreatedAt.Time
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+func (h *IdentityHandler) UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	identityID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid identity id"})
+		return
+	}
+
+	err = h.authService.UnlinkIdentity(c.Request.Context(), userID.(int32), int32(identityID))
+	switch {
+	case errors.Is(err, auth.ErrIdentityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "identity not found"})
+		return
+	case errors.Is(err, auth.ErrLastLoginMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlink identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "identity unlinked successfully"})
+}
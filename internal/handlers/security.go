@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAuthEventPage = 50
+
+type SecurityHandler struct {
+	authService *auth.Service
+}
+
+func NewSecurityHandler(authService *auth.Service) *SecurityHandler {
+	return &SecurityHandler{
+		authService: authService,
+	}
+}
+
+type authEventResponse struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Provider  string    `json:"provider,omitempty"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAuthEventResponse(event sqlc.AuthEvent) authEventResponse {
+	return authEventResponse{
+		ID:        event.ID,
+		Type:      event.EventType,
+		Provider:  event.Provider.String,
+		IPAddress: event.IpAddress.String,
+		UserAgent: event.UserAgent.String,
+		Detail:    event.Detail.String,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+// ListEvents returns the caller's authentication history, newest first. Pass
+// the returned next_before as ?before= to get the next page.
+func (h *SecurityHandler) ListEvents(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	filter, ok := eventPage(c)
+	if !ok {
+		return
+	}
+	filter.UserID = userID.(int32)
+
+	events, err := h.authService.ListAuthEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list events"})
+		return
+	}
+
+	response := make([]authEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, newAuthEventResponse(event))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      response,
+		"next_before": nextEventCursor(events, filter.Limit),
+	})
+}
+
+type adminAuthEventResponse struct {
+	authEventResponse
+	UserID *int32 `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+}
+
+// ListAllEvents is the administrators' view of the audit log. It filters by
+// ?user_id=, ?ip=, and a ?since=/?until= range in RFC 3339, and pages like
+// ListEvents.
+func (h *SecurityHandler) ListAllEvents(c *gin.Context) {
+	filter, ok := eventPage(c)
+	if !ok {
+		return
+	}
+
+	if filter.UserID, ok = queryUserID(c, "user_id"); !ok {
+		return
+	}
+	filter.IPAddress = c.Query("ip")
+
+	for param, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", expected an RFC 3339 timestamp"})
+				return
+			}
+			*dst = t.UTC()
+		}
+	}
+
+	events, err := h.authService.ListAuthEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list events"})
+		return
+	}
+
+	response := make([]adminAuthEventResponse, 0, len(events))
+	for _, event := range events {
+		item := adminAuthEventResponse{
+			authEventResponse: newAuthEventResponse(event),
+			Email:             event.Email.String,
+		}
+		if event.UserID.Valid {
+			item.UserID = &event.UserID.Int32
+		}
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      response,
+		"next_before": nextEventCursor(events, filter.Limit),
+	})
+}
+
+// eventPage reads the ?before= cursor and ?limit= of an event listing.
+func eventPage(c *gin.Context) (auth.AuthEventFilter, bool) {
+	before, limit, ok := pageQuery(c, defaultAuthEventPage, auth.MaxAuthEventPage)
+	return auth.AuthEventFilter{BeforeID: before, Limit: limit}, ok
+}
+
+// pageQuery reads the ?before= cursor and ?limit= of a listing.
+func pageQuery(c *gin.Context, defaultLimit, maxLimit int) (int64, int32, bool) {
+	var beforeID int64
+	if before := c.Query("before"); before != "" {
+		id, err := strconv.ParseInt(before, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before cursor"})
+			return 0, 0, false
+		}
+		beforeID = id
+	}
+
+	n := defaultLimit
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		n, err = strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxLimit)})
+			return 0, 0, false
+		}
+	}
+
+	return beforeID, int32(n), true
+}
+
+// queryUserID reads an optional user ID filter from the query string. It
+// returns 0 when the parameter is absent.
+func queryUserID(c *gin.Context, param string) (int32, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// nextEventCursor returns the cursor of the following page, or nil on the
+// last one.
+func nextEventCursor(events []sqlc.AuthEvent, limit int32) *int64 {
+	if len(events) == 0 || len(events) < int(limit) {
+		return nil
+	}
+	return &events[len(events)-1].ID
+}
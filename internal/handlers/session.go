@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	authService *auth.Service
+}
+
+func NewSessionHandler(authService *auth.Service) *SessionHandler {
+	return &SessionHandler{
+		authService: authService,
+	}
+}
+
+type sessionResponse struct {
+	ID        string     `json:"id"`
+	Provider  string     `json:"provider"`
+	IPAddress string     `json:"ip_address"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt *time.Time `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	Current   bool       `json:"current"`
+}
+
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	currentSessionID := c.GetString(middleware.SessionIDKey)
+
+	sessions, err := h.authService.ListUserSessions(c.Request.Context(), userID.(int32))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": newSessionResponses(sessions, currentSessionID)})
+}
+
+func newSessionResponses(sessions []sqlc.Session, currentSessionID string) []sessionResponse {
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		item := sessionResponse{
+			ID:        auth.SessionHandle(auth.SessionKey(session)),
+			Provider:  session.Provider.String,
+			IPAddress: session.IpAddress.String,
+			UserAgent: session.UserAgent.String,
+			ExpiresAt: session.ExpiresAt,
+			Current:   auth.SessionKey(session) == currentSessionID,
+		}
+		if session.CreatedAt.Valid {
+			item.CreatedAt = &session.CreatedAt.Time
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	handle := c.Param("id")
+	if handle == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session id is required"})
+		return
+	}
+
+	err := h.authService.RevokeUserSession(c.Request.Context(), userID.(int32), handle)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventSessionRevoked)
+	event.Detail = "revoked from session settings"
+	h.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	// Revoking the session in use logs this browser out too.
+	if current := c.GetString(middleware.SessionIDKey); current != "" && handle == auth.SessionHandle(current) {
+		cookieSession, _ := auth.Store.Get(c.Request, auth.SessionName)
+		cookieSession.Options.MaxAge = -1
+		if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+			log.Printf("Failed to clear session cookie: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully"})
+}
+
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherUserSessions(c.Request.Context(), userID.(int32), c.GetString(middleware.SessionIDKey))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	if revoked > 0 {
+		event := middleware.AuthEvent(c, auth.EventSessionRevoked)
+		event.Detail = fmt.Sprintf("revoked %d other sessions", revoked)
+		h.authService.RecordAuthEvent(c.Request.Context(), event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "other sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/mail"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+type sessionTest struct {
+	authService *auth.Service
+	queries     *sqlc.Queries
+	router      *gin.Engine
+}
+
+// newSessionTest mounts the session routes the way the server does, on a
+// fresh database. The test is skipped when Docker is not available.
+func newSessionTest(t *testing.T) sessionTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, queries := dbtest.New(t)
+
+	previousStore := auth.Store
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("c", 32)))
+	t.Cleanup(func() { auth.Store = previousStore })
+
+	mailService, err := mail.NewService(mail.NewMemoryMailer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService := auth.NewService(db, nil, mailService, nil)
+	handler := NewSessionHandler(authService)
+
+	r := gin.New()
+	api := r.Group("/api/sessions", middleware.RequireAuth(authService), middleware.RequireSession())
+	api.GET("", handler.ListSessions)
+	api.POST("/revoke-others", handler.RevokeOtherSessions)
+	api.DELETE("/:id", handler.RevokeSession)
+
+	return sessionTest{authService: authService, queries: queries, router: r}
+}
+
+func (st sessionTest) createUser(t *testing.T, username string) sqlc.User {
+	t.Helper()
+	user, err := st.queries.CreatePasswordUser(context.Background(), sqlc.CreatePasswordUserParams{
+		Username: username,
+		Email:    username + "@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (st sessionTest) login(t *testing.T, user sqlc.User, userAgent string) sqlc.Session {
+	t.Helper()
+	session, err := st.authService.CreateSession(context.Background(), user, auth.PasswordProvider, "192.0.2.1", userAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// do sends a request with the huddle_session cookie of sessionID.
+func (st sessionTest) do(t *testing.T, method, target, sessionID string) *httptest.ResponseRecorder {
+	t.Helper()
+	cookieReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	cookieRec := httptest.NewRecorder()
+	cookieSession, _ := auth.Store.Get(cookieReq, auth.SessionName)
+	cookieSession.Values[auth.SessionIDKey] = sessionID
+	if err := cookieSession.Save(cookieReq, cookieRec); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, target, nil)
+	req.AddCookie(cookieRec.Result().Cookies()[0])
+	rec := httptest.NewRecorder()
+	st.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (st sessionTest) list(t *testing.T, sessionID string) []sessionResponse {
+	t.Helper()
+	rec := st.do(t, http.MethodGet, "/api/sessions", sessionID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: got status %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Sessions []sessionResponse `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Sessions
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	st := newSessionTest(t)
+	user := st.createUser(t, "ada")
+	laptop := st.login(t, user, "laptop")
+	phone := st.login(t, user, "phone")
+
+	listed := st.list(t, phone.ID)
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", listed)
+	}
+	for _, session := range listed {
+		if session.ID == laptop.ID || session.ID == phone.ID {
+			t.Fatal("expected sessions to be listed by handle, not by ID")
+		}
+		if want := session.UserAgent == "phone"; session.Current != want {
+			t.Errorf("%s: current = %v, want %v", session.UserAgent, session.Current, want)
+		}
+	}
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	st := newSessionTest(t)
+	ada := st.login(t, st.createUser(t, "ada"), "laptop")
+	grace := st.login(t, st.createUser(t, "grace"), "laptop")
+
+	rec := st.do(t, http.MethodDelete, "/api/sessions/"+auth.SessionHandle(grace.ID), ada.ID)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if _, err := st.authService.GetSessionByID(context.Background(), grace.ID); err != nil {
+		t.Fatalf("expected the other user's session to be kept, got %v", err)
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	st := newSessionTest(t)
+	user := st.createUser(t, "ada")
+	current := st.login(t, user, "laptop")
+	st.login(t, user, "phone")
+	st.login(t, user, "tablet")
+
+	rec := st.do(t, http.MethodPost, "/api/sessions/revoke-others", current.ID)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"revoked":2`) {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	listed := st.list(t, current.ID)
+	if len(listed) != 1 || !listed[0].Current {
+		t.Fatalf("expected only the current session to be left, got %+v", listed)
+	}
+}
+
+func TestRevokeCurrentSessionClearsCookie(t *testing.T) {
+	st := newSessionTest(t)
+	user := st.createUser(t, "ada")
+	current := st.login(t, user, "laptop")
+	other := st.login(t, user, "phone")
+
+	// Revoking another session leaves the cookie alone.
+	rec := st.do(t, http.MethodDelete, "/api/sessions/"+auth.SessionHandle(other.ID), current.ID)
+	if rec.Code != http.StatusOK || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("other session: got status %d, cookies %v", rec.Code, rec.Result().Cookies())
+	}
+
+	rec = st.do(t, http.MethodDelete, "/api/sessions/"+auth.SessionHandle(current.ID), current.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("current session: got status %d: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.SessionName || cookies[0].MaxAge >= 0 {
+		t.Fatalf("expected the session cookie to be cleared, got %v", cookies)
+	}
+
+	if rec := st.do(t, http.MethodGet, "/api/sessions", current.ID); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session to be logged out, got status %d", rec.Code)
+	}
+}
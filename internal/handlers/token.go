@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TokenHandler struct {
+	authService *auth.Service
+}
+
+func NewTokenHandler(authService *auth.Service) *TokenHandler {
+	return &TokenHandler{
+		authService: authService,
+	}
+}
+
+type tokenResponse struct {
+	ID         int32      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+func newTokenResponse(token sqlc.PersonalAccessToken) tokenResponse {
+	response := tokenResponse{
+		ID:     token.ID,
+		Name:   token.Name,
+		Prefix: token.TokenPrefix,
+		Scopes: auth.SplitScopes(token.Scopes),
+	}
+	if token.LastUsedAt.Valid {
+		response.LastUsedAt = &token.LastUsedAt.Time
+	}
+	if token.ExpiresAt.Valid {
+		response.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.CreatedAt.Valid {
+		response.CreatedAt = &token.CreatedAt.Time
+	}
+	return response
+}
+
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	tokens, err := h.authService.ListAccessTokens(c.Request.Context(), userID.(int32))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
+		return
+	}
+
+	response := make([]tokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, newTokenResponse(token))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens":           response,
+		"available_scopes": auth.Scopes,
+	})
+}
+
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name" binding:"required,max=100"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, row, err := h.authService.CreateAccessToken(c.Request.Context(), userID.(int32), req.Name, req.Scopes, expiresAt)
+	switch {
+	case errors.Is(err, auth.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrTooManyAccessTokens):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventTokenCreated)
+	event.Detail = fmt.Sprintf("personal access token %q (%s)", row.Name, row.Scopes)
+	h.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "token created, copy it now as it will not be shown again",
+		"token":      token,
+		"token_info": newTokenResponse(row),
+	})
+}
+
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+
+	err = h.authService.RevokeAccessToken(c.Request.Context(), userID.(int32), int32(tokenID))
+	if errors.Is(err, auth.ErrAccessTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked successfully"})
+}
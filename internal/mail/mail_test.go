@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testTemplates(t *testing.T) *Templates {
+	t.Helper()
+
+	templates, err := ParseTemplates(fstest.MapFS{
+		"templates/greeting.txt":  {Data: []byte(`{{define "greeting.subject"}}Hello {{.Name}}{{end}}Hi {{.Name}}, welcome aboard.`)},
+		"templates/greeting.html": {Data: []byte(`<p>Hi {{.Name}}, welcome aboard.</p>`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+func TestRender(t *testing.T) {
+	msg, err := testTemplates(t).Render("greeting", map[string]string{"Name": "<Ada>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "Hello <Ada>" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if msg.Text != "Hi <Ada>, welcome aboard." {
+		t.Errorf("unexpected text %q", msg.Text)
+	}
+	if msg.HTML != "<p>Hi &lt;Ada&gt;, welcome aboard.</p>" {
+		t.Errorf("expected escaped html, got %q", msg.HTML)
+	}
+}
+
+func TestServiceSendsToMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	service := &Service{mailer: mailer, templates: testTemplates(t)}
+
+	if err := service.Send(context.Background(), "ada@example.com", "greeting", map[string]string{"Name": "Ada"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	if messages[0].To != "ada@example.com" || messages[0].Subject != "Hello Ada" {
+		t.Errorf("unexpected message %+v", messages[0])
+	}
+}
+
+func TestEmbeddedTemplatesParse(t *testing.T) {
+	if _, err := ParseTemplates(templateFS); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildMIME(t *testing.T) {
+	body, err := buildMIME("Huddle <no-reply@example.com>", Message{
+		To:      "ada@example.com",
+		Subject: "Hello",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"To: ada@example.com", "Subject: Hello", "multipart/alternative", "plain body", "<p>html body</p>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+
+	if _, err := buildMIME("no-reply@example.com", Message{To: "ada@example.com\r\nBcc: eve@example.com"}); err == nil {
+		t.Error("expected header injection in recipient to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates holds the email templates. Every email <name> is made of
+// templates/<name>.txt and templates/<name>.html; the text file also defines
+// a "<name>.subject" template for the subject line.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func ParseTemplates(fsys fs.FS) (*Templates, error) {
+	text, err := texttemplate.ParseFS(fsys, "templates/*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing text templates: %w", err)
+	}
+	html, err := htmltemplate.ParseFS(fsys, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing html templates: %w", err)
+	}
+	return &Templates{text: text, html: html}, nil
+}
+
+// Render builds the message for template name. The recipient is left empty.
+func (t *Templates) Render(name string, data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+
+	if err := t.text.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("error rendering subject of %s: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("error rendering text of %s: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("error rendering html of %s: %w", name, err)
+	}
+
+	return Message{
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Service renders transactional emails from the embedded templates and hands
+// them to a Mailer.
+type Service struct {
+	mailer    Mailer
+	templates *Templates
+}
+
+// NewService parses the embedded templates and returns a Service sending
+// through mailer.
+func NewService(mailer Mailer) (*Service, error) {
+	templates, err := ParseTemplates(templateFS)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{mailer: mailer, templates: templates}, nil
+}
+
+func (s *Service) Send(ctx context.Context, to, template string, data any) error {
+	msg, err := s.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("error sending %s to %s: %w", template, to, err)
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"huddle-backend/internal/auth"
 
@@ -9,12 +15,40 @@ import (
 )
 
 const (
-	UserIDKey    = "user_id"
-	SessionIDKey = "session_id"
+	UserIDKey        = "user_id"
+	SessionIDKey     = "session_id"
+	EmailVerifiedKey = "email_verified"
+	AuthMethodKey    = "auth_method"
+	ScopesKey        = "scopes"
+	GrantsKey        = "grants"
+	// ImpersonationKey holds an auth.Impersonation on requests made while
+	// an admin impersonates the user.
+	ImpersonationKey = "impersonation"
 )
 
+// Values stored under AuthMethodKey.
+const (
+	AuthMethodSession = "session"
+	AuthMethodToken   = "token"
+	AuthMethodJWT     = "jwt"
+)
+
+// RequireAuth accepts the huddle_session cookie, or a bearer token in the
+// Authorization header: either a personal access token, limited to its scopes
+// (see RequireScope), or a JWT access token from the token endpoint, which is
+// verified from its claims alone. Suspended users are rejected with 403; JWTs
+// are refused to them when issued and lapse with their short TTL.
 func RequireAuth(authService *auth.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if token, ok := bearerToken(c); ok {
+			if strings.HasPrefix(token, auth.AccessTokenPrefix) {
+				authenticateAccessToken(c, authService, token)
+			} else {
+				authenticateJWT(c, authService, token)
+			}
+			return
+		}
+
 		cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -35,9 +69,266 @@ func RequireAuth(authService *auth.Service) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// Suspending a user deletes their sessions, but a suspension must
+		// hold even if one slipped through.
+		if auth.Suspended(sessionData.SuspendedAt, sessionData.SuspendedUntil, time.Now()) {
+			rejectSuspended(c)
+			return
+		}
+		if sessionData.MfaPending {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "two-factor authentication required", "code": "two_factor_required"})
+			c.Abort()
+			return
+		}
+
+		expiresAt, renewed, err := authService.RenewSession(c.Request.Context(), sessionData)
+		if err != nil {
+			log.Printf("Failed to renew session: %v", err)
+		}
+		if renewed {
+			cookieSession.Options.MaxAge = int(time.Until(expiresAt).Seconds())
+			if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+				log.Printf("Failed to re-issue session cookie: %v", err)
+			}
+		}
 
 		c.Set(UserIDKey, sessionData.UserID)
 		c.Set(SessionIDKey, sessionID)
+		c.Set(EmailVerifiedKey, sessionData.EmailVerifiedAt.Valid)
+		c.Set(AuthMethodKey, AuthMethodSession)
+		c.Set(GrantsKey, auth.SessionGrants(sessionData.Roles, sessionData.Permissions))
+
+		if !sessionData.ImpersonatorID.Valid {
+			c.Next()
+			return
+		}
+
+		if err := authService.CheckImpersonator(c.Request.Context(), sessionData.ImpersonatorID.Int32); err != nil {
+			if !errors.Is(err, auth.ErrImpersonationRevoked) {
+				log.Printf("Failed to check impersonator: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check impersonation"})
+				c.Abort()
+				return
+			}
+			authService.DeleteSession(c.Request.Context(), sessionID)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "impersonation_revoked"})
+			c.Abort()
+			return
+		}
+
+		// Impersonation sessions carry no roles. AuditImpersonation records
+		// the request.
+		c.Set(GrantsKey, auth.Grants{})
+		c.Set(ImpersonationKey, auth.Impersonation{
+			ImpersonatorID: sessionData.ImpersonatorID.Int32,
+			ExpiresAt:      sessionData.ExpiresAt,
+		})
+		c.Next()
+	}
+}
+
+// AuditImpersonation records every request made with an impersonation cookie
+// in the admin audit trail under the impersonator. It runs on every route,
+// not just behind RequireAuth, so that /auth/logout, /auth/token and requests
+// turned away once the impersonation expired are recorded too. Requests that
+// authenticate with a bearer token do not use the cookie and are skipped.
+func AuditImpersonation(authService *auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := bearerToken(c); ok {
+			c.Next()
+			return
+		}
+		cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+		if err != nil {
+			c.Next()
+			return
+		}
+		impersonatorID, userID, ok := auth.CookieImpersonation(cookieSession)
+		if !ok {
+			c.Next()
+			return
+		}
 		c.Next()
+
+		entry := AdminAction(c, auth.ActionImpersonatedRequest, userID)
+		entry.AdminID = impersonatorID
+		entry.Detail = fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+		authService.RecordAdminAction(c.Request.Context(), entry)
+	}
+}
+
+// RequireScope limits a route to access tokens that were granted scope.
+// Cookie sessions pass, since they act with the user's full rights. It must
+// run after RequireAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(AuthMethodKey) == AuthMethodToken && !slices.Contains(c.GetStringSlice(ScopesKey), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access token is missing the required scope", "scope": scope})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects personal access tokens and impersonation sessions.
+// It guards account security settings, so that neither a leaked token nor an
+// impersonating admin can take over the account. Cookie sessions and JWT
+// access tokens both come from an interactive login and pass. It must run
+// after RequireAuth.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(AuthMethodKey) == AuthMethodToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint cannot be used with a personal access token"})
+			c.Abort()
+			return
+		}
+		if _, ok := c.Get(ImpersonationKey); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrImpersonating.Error(), "code": "impersonating"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole limits a route to users holding role. Roles come from the
+// session or JWT access token, so checking them costs no query. Personal
+// access tokens carry no roles and are always rejected. It must run after
+// RequireAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Grants(c).HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "role": role})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission limits a route to users whose roles grant permission.
+// Like RequireRole, it must run after RequireAuth.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Grants(c).Can(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "permission": permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Grants returns the roles and permissions of the authenticated user.
+func Grants(c *gin.Context) auth.Grants {
+	grants, _ := c.Get(GrantsKey)
+	g, _ := grants.(auth.Grants)
+	return g
+}
+
+func authenticateAccessToken(c *gin.Context, authService *auth.Service, token string) {
+	accessToken, err := authService.AuthenticateAccessToken(c.Request.Context(), token)
+	if errors.Is(err, auth.ErrAccountSuspended) {
+		rejectSuspended(c)
+		return
+	}
+	if errors.Is(err, auth.ErrDeletionScheduled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "deletion_scheduled"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		if err != auth.ErrInvalidAccessToken {
+			log.Printf("Failed to authenticate access token: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+		c.Abort()
+		return
+	}
+
+	c.Set(UserIDKey, accessToken.UserID)
+	c.Set(EmailVerifiedKey, accessToken.EmailVerified)
+	c.Set(AuthMethodKey, AuthMethodToken)
+	c.Set(ScopesKey, accessToken.Scopes)
+	c.Next()
+}
+
+func authenticateJWT(c *gin.Context, authService *auth.Service, token string) {
+	claims, err := authService.VerifyAccessToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+		c.Abort()
+		return
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+		c.Abort()
+		return
+	}
+
+	c.Set(UserIDKey, userID)
+	c.Set(SessionIDKey, claims.SessionID)
+	c.Set(EmailVerifiedKey, claims.EmailVerified)
+	c.Set(AuthMethodKey, AuthMethodJWT)
+	c.Set(GrantsKey, claims.Grants())
+	c.Next()
+}
+
+func rejectSuspended(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrAccountSuspended.Error(), "code": "account_suspended"})
+	c.Abort()
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// RequireVerifiedEmail rejects users who have not verified their email yet.
+// It must run after RequireAuth.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(EmailVerifiedKey) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email address not verified", "code": "email_unverified"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthEvent describes the current request as an audit log entry of
+// eventType, attributed to the authenticated user if there is one.
+func AuthEvent(c *gin.Context, eventType string) auth.AuthEvent {
+	event := auth.AuthEvent{
+		Type:      eventType,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if userID, ok := c.Get(UserIDKey); ok {
+		event.UserID = userID.(int32)
+	}
+	return event
+}
+
+// AdminAction describes the current request as an admin audit trail entry:
+// the authenticated admin performing action on targetUserID.
+func AdminAction(c *gin.Context, action string, targetUserID int32) auth.AdminAction {
+	entry := auth.AdminAction{
+		Action:       action,
+		TargetUserID: targetUserID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	}
+	if userID, ok := c.Get(UserIDKey); ok {
+		entry.AdminID = userID.(int32)
 	}
+	return entry
 }
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/mail"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// newAuthTestService returns an auth.Service on a fresh database, with a
+// cookie store RequireAuth can read. The test is skipped when Docker is not
+// available.
+func newAuthTestService(t *testing.T) (*auth.Service, *sqlc.Queries) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, queries := dbtest.New(t)
+
+	previousStore := auth.Store
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("c", 32)))
+	t.Cleanup(func() { auth.Store = previousStore })
+
+	mailService, err := mail.NewService(mail.NewMemoryMailer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := auth.NewJWTSigner([]byte(strings.Repeat("k", 32)), 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth.NewService(db, nil, mailService, signer), queries
+}
+
+func createTestUser(t *testing.T, queries *sqlc.Queries, username string) sqlc.User {
+	t.Helper()
+	user, err := queries.CreatePasswordUser(context.Background(), sqlc.CreatePasswordUserParams{
+		Username: username,
+		Email:    username + "@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// sessionCookie returns the huddle_session cookie a browser holds for
+// sessionID.
+func sessionCookie(t *testing.T, sessionID string) *http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	cookieSession, _ := auth.Store.Get(req, auth.SessionName)
+	cookieSession.Values[auth.SessionIDKey] = sessionID
+	if err := cookieSession.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Result().Cookies()[0]
+}
+
+// serveAuthenticated sends req through RequireAuth and the given guards to a
+// handler that answers 204.
+func serveAuthenticated(authService *auth.Service, req *http.Request, guards ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := gin.New()
+	handlers := append([]gin.HandlerFunc{RequireAuth(authService)}, guards...)
+	handlers = append(handlers, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	r.Handle(req.Method, req.URL.Path, handlers...)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	support := auth.Grants{
+		Roles:       []string{auth.RoleSupport},
+		Permissions: []string{auth.PermissionAdminAccess, auth.PermissionUsersRead},
+	}
+
+	tests := []struct {
+		name   string
+		grants *auth.Grants
+		guard  gin.HandlerFunc
+		want   int
+	}{
+		{"granted permission", &support, RequirePermission(auth.PermissionUsersRead), http.StatusNoContent},
+		{"missing permission", &support, RequirePermission(auth.PermissionUsersWrite), http.StatusForbidden},
+		{"granted role", &support, RequireRole(auth.RoleSupport), http.StatusNoContent},
+		{"missing role", &support, RequireRole(auth.RoleAdmin), http.StatusForbidden},
+		{"no grants", nil, RequirePermission(auth.PermissionAdminAccess), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/admin", func(c *gin.Context) {
+				if tt.grants != nil {
+					c.Set(GrantsKey, *tt.grants)
+				}
+			}, tt.guard, func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSessionRejectsImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		setup func(c *gin.Context)
+		want  int
+	}{
+		{"cookie session", func(c *gin.Context) {
+			c.Set(AuthMethodKey, AuthMethodSession)
+		}, http.StatusNoContent},
+		{"personal access token", func(c *gin.Context) {
+			c.Set(AuthMethodKey, AuthMethodToken)
+		}, http.StatusForbidden},
+		{"impersonation", func(c *gin.Context) {
+			c.Set(AuthMethodKey, AuthMethodSession)
+			c.Set(ImpersonationKey, auth.Impersonation{ImpersonatorID: 1})
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/api/2fa/enroll", tt.setup, RequireSession(), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/2fa/enroll", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAuthRejectsPendingTwoFactor(t *testing.T) {
+	authService, queries := newAuthTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, queries, "ada")
+
+	withTwoFactor := user
+	withTwoFactor.TotpEnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	pending, err := authService.CreateSession(ctx, withTwoFactor, auth.PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(sessionCookie(t, pending.ID))
+	rec := serveAuthenticated(authService, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "two_factor_required") {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	full, err := authService.CreateSession(ctx, user, auth.PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(sessionCookie(t, full.ID))
+	if rec := serveAuthenticated(authService, req); rec.Code != http.StatusNoContent {
+		t.Fatalf("full session: got status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestRequireAuthRenewsCookie checks that the cookie is only re-issued when the
+// session was slid forward.
+func TestRequireAuthRenewsCookie(t *testing.T) {
+	authService, queries := newAuthTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, queries, "ada")
+
+	session, err := authService.CreateSession(ctx, user, auth.PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(sessionCookie(t, session.ID))
+	rec := serveAuthenticated(authService, req)
+	if rec.Code != http.StatusNoContent || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("fresh session: got status %d, cookies %v", rec.Code, rec.Result().Cookies())
+	}
+
+	// Close to expiry, as if the user had been away for most of a week.
+	if err := queries.ExtendSession(ctx, sqlc.ExtendSessionParams{ID: session.ID, ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(sessionCookie(t, session.ID))
+	rec = serveAuthenticated(authService, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expiring session: got status %d: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.SessionName || cookies[0].MaxAge <= 60 {
+		t.Fatalf("expected the session cookie to be re-issued, got %v", cookies)
+	}
+
+	renewed, err := authService.GetSessionByID(ctx, session.ID)
+	if err != nil || time.Until(renewed.ExpiresAt) <= time.Minute {
+		t.Fatalf("expected the session to be extended, got %+v: %v", renewed, err)
+	}
+}
+
+func TestRequireAuthAccessTokens(t *testing.T) {
+	authService, queries := newAuthTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, queries, "ada")
+
+	issue := func(scopes []string, expiresAt *time.Time) string {
+		t.Helper()
+		token, _, err := authService.CreateAccessToken(ctx, user.ID, "script", scopes, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := issue([]string{auth.ScopeProfilesRead}, nil)
+	expiredAt := time.Now().Add(-time.Minute)
+	expired := issue([]string{auth.ScopeProfilesRead}, &expiredAt)
+	revoked, row, err := authService.CreateAccessToken(ctx, user.ID, "old script", []string{auth.ScopeProfilesRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authService.RevokeAccessToken(ctx, user.ID, row.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		scope string
+		want  int
+	}{
+		{"valid token", valid, auth.ScopeProfilesRead, http.StatusNoContent},
+		{"missing scope", valid, auth.ScopeProfilesWrite, http.StatusForbidden},
+		{"revoked token", revoked, auth.ScopeProfilesRead, http.StatusUnauthorized},
+		{"expired token", expired, auth.ScopeProfilesRead, http.StatusUnauthorized},
+		{"unknown token", auth.AccessTokenPrefix + "unknown", auth.ScopeProfilesRead, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/profiles", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := serveAuthenticated(authService, req, RequireScope(tt.scope))
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestAccessTokenLastUsedThrottled checks that a busy token does not write
+// last_used_at on every request.
+func TestAccessTokenLastUsedThrottled(t *testing.T) {
+	authService, queries := newAuthTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, queries, "ada")
+
+	token, _, err := authService.CreateAccessToken(ctx, user.ID, "script", []string{auth.ScopeProfilesRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := func() sql.NullTime {
+		t.Helper()
+		tokens, err := authService.ListAccessTokens(ctx, user.ID)
+		if err != nil || len(tokens) != 1 {
+			t.Fatalf("expected one token, got %d: %v", len(tokens), err)
+		}
+		return tokens[0].LastUsedAt
+	}
+	use := func() {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/profiles", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if rec := serveAuthenticated(authService, req); rec.Code != http.StatusNoContent {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if lastUsed().Valid {
+		t.Fatal("expected a new token to be unused")
+	}
+	use()
+	first := lastUsed()
+	if !first.Valid {
+		t.Fatal("expected the first use to be recorded")
+	}
+	use()
+	if second := lastUsed(); !second.Time.Equal(first.Time) {
+		t.Fatalf("expected last_used_at to stay at %v within a minute, got %v", first.Time, second.Time)
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	authService, queries := newAuthTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, queries, "ada")
+
+	session, err := authService.CreateSession(ctx, user, auth.PasswordProvider, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/huddles", nil)
+	req.AddCookie(sessionCookie(t, session.ID))
+	rec := serveAuthenticated(authService, req, RequireVerifiedEmail())
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "email_unverified") {
+		t.Fatalf("unverified: got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := queries.MarkUserEmailVerified(ctx, sqlc.MarkUserEmailVerifiedParams{ID: user.ID, Email: user.Email}); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/huddles", nil)
+	req.AddCookie(sessionCookie(t, session.ID))
+	if rec := serveAuthenticated(authService, req, RequireVerifiedEmail()); rec.Code != http.StatusNoContent {
+		t.Fatalf("verified: got status %d: %s", rec.Code, rec.Body.String())
+	}
+}
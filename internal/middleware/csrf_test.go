@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"huddle-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+const testOrigin = "http://localhost:5173"
+
+func csrfTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("k", 32)))
+
+	r := gin.New()
+	r.Use(CSRF([]string{testOrigin}))
+	r.GET("/csrf", func(c *gin.Context) {
+		token, err := IssueCSRFToken(c)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, token)
+	})
+	r.POST("/action", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestCSRF(t *testing.T) {
+	r := csrfTestRouter()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("issuing token: status %d", rec.Code)
+	}
+	token := rec.Body.String()
+	cookie := rec.Result().Cookies()[0]
+
+	tests := []struct {
+		name   string
+		origin string
+		token  string
+		cookie bool
+		bearer bool
+		want   int
+	}{
+		{name: "valid token", origin: testOrigin, token: token, cookie: true, want: http.StatusNoContent},
+		{name: "no origin header", token: token, cookie: true, want: http.StatusNoContent},
+		{name: "missing token", origin: testOrigin, cookie: true, want: http.StatusForbidden},
+		{name: "wrong token", origin: testOrigin, token: "forged", cookie: true, want: http.StatusForbidden},
+		{name: "untrusted origin", origin: "https://evil.example", token: token, cookie: true, want: http.StatusForbidden},
+		{name: "untrusted origin without cookie", origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "no cookie", origin: testOrigin, want: http.StatusNoContent},
+		{name: "bearer token", origin: "https://evil.example", cookie: true, bearer: true, want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/action", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.token != "" {
+				req.Header.Set(CSRFHeaderName, tt.token)
+			}
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFCrossSiteFetch(t *testing.T) {
+	r := csrfTestRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/action", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitIP throttles requests per client IP. If the limiter's store
+// fails, requests are let through rather than locking everybody out.
+func RateLimitIP(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wait, err := limiter.AllowIP(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			log.Printf("Rate limiter error: %v", err)
+		}
+		if wait > 0 {
+			TooManyRequests(c, wait)
+			return
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests answers 429 with a Retry-After header in whole seconds.
+func TooManyRequests(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many attempts, try again later",
+		"retry_after": seconds,
+	})
+	c.Abort()
+}
@@ -9,6 +9,11 @@ import (
 	"huddle-backend/internal/database/sqlc"
 )
 
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
 type Service struct {
 	queries *sqlc.Queries
 }
@@ -147,11 +152,29 @@ func (s *Service) SearchProfilesByUsername(ctx context.Context, searchTerm strin
 
 func (s *Service) ValidateUsername(ctx context.Context, username string) error {
 
-	if len(username) < 3 {
-		return fmt.Errorf("username must be at least 3 characters long")
+	if err := ValidateUsernameFormat(username); err != nil {
+		return err
+	}
+
+	available, err := s.CheckUsernameAvailability(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return fmt.Errorf("username '%s' is already taken", username)
+	}
+
+	return nil
+}
+
+// ValidateUsernameFormat checks the length and character rules for usernames
+// without checking availability.
+func ValidateUsernameFormat(username string) error {
+	if len(username) < MinUsernameLength {
+		return fmt.Errorf("username must be at least %d characters long", MinUsernameLength)
 	}
-	if len(username) > 30 {
-		return fmt.Errorf("username must not exceed 30 characters")
+	if len(username) > MaxUsernameLength {
+		return fmt.Errorf("username must not exceed %d characters", MaxUsernameLength)
 	}
 
 	for _, char := range username {
@@ -163,13 +186,5 @@ func (s *Service) ValidateUsername(ctx context.Context, username string) error {
 		}
 	}
 
-	available, err := s.CheckUsernameAvailability(ctx, username)
-	if err != nil {
-		return err
-	}
-	if !available {
-		return fmt.Errorf("username '%s' is already taken", username)
-	}
-
 	return nil
 }
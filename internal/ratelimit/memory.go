@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps state in process. Every instance counts on its own, so
+// it only suits single-instance deployments.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		states: make(map[string]State),
+	}
+}
+
+func (m *MemoryStore) Update(ctx context.Context, key string, fn func(s *State)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := m.states[key]
+	fn(&state)
+	m.states[key] = state
+	return nil
+}
+
+func (m *MemoryStore) Purge(ctx context.Context, idleSince, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for key, state := range m.states {
+		if state.UpdatedAt.Before(idleSince) && !state.LockedUntil.After(now) {
+			delete(m.states, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"huddle-backend/internal/database/sqlc"
+)
+
+// PostgresStore keeps state in the rate_limits table, shared by every
+// instance. Each update locks its row for the duration of a short
+// transaction.
+type PostgresStore struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db, queries: sqlc.New(db)}
+}
+
+func (p *PostgresStore) Update(ctx context.Context, key string, fn func(s *State)) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := p.queries.WithTx(tx)
+
+	// Create the row first so that concurrent updates of a new key also
+	// serialize on its lock.
+	if err := q.CreateRateLimit(ctx, key); err != nil {
+		return fmt.Errorf("error creating rate limit: %w", err)
+	}
+
+	row, err := q.GetRateLimitForUpdate(ctx, key)
+	if err != nil {
+		return fmt.Errorf("error reading rate limit: %w", err)
+	}
+	state := State{
+		Tokens:      row.Tokens,
+		Failures:    int(row.Failures),
+		LockedUntil: row.LockedUntil.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+
+	fn(&state)
+
+	err = q.UpdateRateLimit(ctx, sqlc.UpdateRateLimitParams{
+		Key:         key,
+		Tokens:      state.Tokens,
+		Failures:    int32(state.Failures),
+		LockedUntil: sql.NullTime{Time: state.LockedUntil, Valid: !state.LockedUntil.IsZero()},
+		UpdatedAt:   sql.NullTime{Time: state.UpdatedAt, Valid: !state.UpdatedAt.IsZero()},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving rate limit: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (p *PostgresStore) Purge(ctx context.Context, idleSince, now time.Time) (int64, error) {
+	purged, err := p.queries.PurgeRateLimits(ctx, sqlc.PurgeRateLimitsParams{
+		IdleSince: sql.NullTime{Time: idleSince, Valid: true},
+		Now:       sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error purging rate limits: %w", err)
+	}
+	return purged, nil
+}
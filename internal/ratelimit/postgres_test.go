@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/dbtest"
+)
+
+func TestPostgresStoreUpdate(t *testing.T) {
+	db, _ := dbtest.New(t)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	err := store.Update(ctx, "account:ada@example.com", func(s *State) {
+		if !s.UpdatedAt.IsZero() || s.Tokens != 0 || s.Failures != 0 {
+			t.Errorf("expected a new key to start empty, got %+v", *s)
+		}
+		s.Tokens = 2.5
+		s.Failures = 3
+		s.LockedUntil = now.Add(time.Minute)
+		s.UpdatedAt = now
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Update(ctx, "account:ada@example.com", func(s *State) {
+		if s.Tokens != 2.5 || s.Failures != 3 || !s.LockedUntil.Equal(now.Add(time.Minute)) || !s.UpdatedAt.Equal(now) {
+			t.Errorf("expected the saved state back, got %+v", *s)
+		}
+		s.LockedUntil = time.Time{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Update(ctx, "account:ada@example.com", func(s *State) {
+		if !s.LockedUntil.IsZero() {
+			t.Errorf("expected the lockout to be cleared, got %v", s.LockedUntil)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPostgresStoreSerializesUpdates races updates of one key, new at first,
+// the way several instances would.
+func TestPostgresStoreSerializesUpdates(t *testing.T) {
+	db, _ := dbtest.New(t)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Update(ctx, "ip:192.0.2.1", func(s *State) { s.Failures++ }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	store.Update(ctx, "ip:192.0.2.1", func(s *State) {
+		if s.Failures != updates {
+			t.Errorf("expected %d failures, got %d", updates, s.Failures)
+		}
+	})
+}
+
+func TestPostgresStorePurge(t *testing.T) {
+	db, _ := dbtest.New(t)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	idleSince := now.Add(-time.Hour)
+
+	set := func(key string, updatedAt, lockedUntil time.Time) {
+		t.Helper()
+		err := store.Update(ctx, key, func(s *State) {
+			s.UpdatedAt = updatedAt
+			s.LockedUntil = lockedUntil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	set("ip:idle", now.Add(-2*time.Hour), time.Time{})
+	set("ip:lockout-over", now.Add(-2*time.Hour), now.Add(-time.Minute))
+	set("ip:never-used", time.Time{}, time.Time{})
+	set("ip:recent", now.Add(-time.Minute), time.Time{})
+	set("ip:locked", now.Add(-2*time.Hour), now.Add(time.Hour))
+
+	purged, err := store.Purge(ctx, idleSince, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 3 {
+		t.Fatalf("purged %d keys, want 3", purged)
+	}
+
+	for key, kept := range map[string]bool{"ip:idle": false, "ip:lockout-over": false, "ip:recent": true, "ip:locked": true} {
+		store.Update(ctx, key, func(s *State) {
+			if existed := !s.UpdatedAt.IsZero(); existed != kept {
+				t.Errorf("%s: kept = %v, want %v", key, existed, kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package ratelimit throttles requests with token buckets and locks accounts
+// out after repeated failed logins. State lives in a Store: in memory for a
+// single instance, or in Postgres when several instances share the load.
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"huddle-backend/internal/config"
+)
+
+// Rate is a token bucket: Burst requests at once, then one more every Every.
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+// Policy holds the limits of the auth endpoints. After LockoutThreshold
+// failures in a row an account is locked for LockoutBase, doubled for every
+// further failure up to LockoutMax. Failures are forgotten after FailureTTL
+// without any attempt on the account.
+type Policy struct {
+	IP               Rate
+	Account          Rate
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+	FailureTTL       time.Duration
+}
+
+func LoadPolicy() Policy {
+	policy := Policy{
+		IP: Rate{
+			Burst: config.Int("AUTH_RATE_LIMIT_IP_BURST", 30),
+			Every: config.Duration("AUTH_RATE_LIMIT_IP_EVERY", 2*time.Second),
+		},
+		Account: Rate{
+			Burst: config.Int("AUTH_RATE_LIMIT_ACCOUNT_BURST", 10),
+			Every: config.Duration("AUTH_RATE_LIMIT_ACCOUNT_EVERY", 30*time.Second),
+		},
+		LockoutThreshold: config.Int("AUTH_LOCKOUT_THRESHOLD", 5),
+		LockoutBase:      config.Duration("AUTH_LOCKOUT_BASE", time.Minute),
+		LockoutMax:       config.Duration("AUTH_LOCKOUT_MAX", time.Hour),
+		FailureTTL:       config.Duration("AUTH_LOCKOUT_RESET_AFTER", 24*time.Hour),
+	}
+
+	if policy.LockoutMax < policy.LockoutBase {
+		policy.LockoutMax = policy.LockoutBase
+	}
+	return policy
+}
+
+// State is what a Store keeps per key. A zero UpdatedAt means the key is new.
+type State struct {
+	Tokens      float64
+	Failures    int
+	LockedUntil time.Time
+	UpdatedAt   time.Time
+}
+
+// Store persists State. Update must apply fn atomically with respect to
+// other updates of the same key. Purge drops keys last updated before
+// idleSince that are not locked at now.
+type Store interface {
+	Update(ctx context.Context, key string, fn func(s *State)) error
+	Purge(ctx context.Context, idleSince, now time.Time) (int64, error)
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// FromEnv builds a limiter with LoadPolicy and the store named by
+// RATE_LIMIT_STORE: "memory" (the default) or "postgres".
+func FromEnv(db *sql.DB) *Limiter {
+	var store Store
+	switch backend := os.Getenv("RATE_LIMIT_STORE"); backend {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "postgres":
+		store = NewPostgresStore(db)
+	default:
+		log.Fatalf("rate limiting: unknown RATE_LIMIT_STORE %q", backend)
+	}
+	return New(store, LoadPolicy())
+}
+
+// Purge forgets keys that have been idle for longer than any bucket refill or
+// failure count lasts. It runs as a background job.
+func (l *Limiter) Purge(ctx context.Context) (int64, error) {
+	now := l.now()
+	idle := max(l.policy.FailureTTL, time.Duration(l.policy.IP.Burst)*l.policy.IP.Every,
+		time.Duration(l.policy.Account.Burst)*l.policy.Account.Every)
+	return l.store.Purge(ctx, now.Add(-idle), now)
+}
+
+// AllowIP takes a token from the bucket of ip. It returns how long the
+// client has to wait, or 0 when the request may proceed.
+func (l *Limiter) AllowIP(ctx context.Context, ip string) (time.Duration, error) {
+	return l.take(ctx, "ip:"+ip, l.policy.IP)
+}
+
+// AllowAccount checks that account is not locked out and takes a token from
+// its bucket. It returns how long the client has to wait, or 0.
+func (l *Limiter) AllowAccount(ctx context.Context, account string) (time.Duration, error) {
+	var wait time.Duration
+	err := l.store.Update(ctx, "account:"+account, func(s *State) {
+		now := l.now()
+		if s.LockedUntil.After(now) {
+			wait = s.LockedUntil.Sub(now)
+			return
+		}
+		wait = takeToken(s, l.policy.Account, now)
+	})
+	return wait, err
+}
+
+// Failure counts a failed attempt on account. When it locks the account, it
+// returns the length of the lockout.
+func (l *Limiter) Failure(ctx context.Context, account string) (time.Duration, error) {
+	var lockout time.Duration
+	err := l.store.Update(ctx, "account:"+account, func(s *State) {
+		now := l.now()
+		if !s.UpdatedAt.IsZero() && now.Sub(s.UpdatedAt) > l.policy.FailureTTL {
+			s.Failures = 0
+		}
+		refill(s, l.policy.Account, now)
+		s.Failures++
+
+		lockout = l.policy.lockout(s.Failures)
+		if lockout > 0 {
+			s.LockedUntil = now.Add(lockout)
+		}
+	})
+	return lockout, err
+}
+
+// Success clears the failures of account after a successful login.
+func (l *Limiter) Success(ctx context.Context, account string) error {
+	return l.store.Update(ctx, "account:"+account, func(s *State) {
+		refill(s, l.policy.Account, l.now())
+		s.Failures = 0
+		s.LockedUntil = time.Time{}
+	})
+}
+
+func (l *Limiter) take(ctx context.Context, key string, rate Rate) (time.Duration, error) {
+	var wait time.Duration
+	err := l.store.Update(ctx, key, func(s *State) {
+		wait = takeToken(s, rate, l.now())
+	})
+	return wait, err
+}
+
+// lockout returns how long the given number of consecutive failures locks an
+// account for.
+func (p Policy) lockout(failures int) time.Duration {
+	if p.LockoutThreshold <= 0 || failures < p.LockoutThreshold {
+		return 0
+	}
+
+	lockout := p.LockoutBase
+	for i := p.LockoutThreshold; i < failures && lockout < p.LockoutMax; i++ {
+		lockout *= 2
+	}
+	return min(lockout, p.LockoutMax)
+}
+
+// takeToken takes one token from the bucket. It returns 0 on success, or how
+// long until a token is available. A zero rate disables the limit.
+func takeToken(s *State, rate Rate, now time.Time) time.Duration {
+	if rate.Burst <= 0 || rate.Every <= 0 {
+		return 0
+	}
+
+	refill(s, rate, now)
+	if s.Tokens >= 1 {
+		s.Tokens--
+		return 0
+	}
+	return time.Duration((1 - s.Tokens) * float64(rate.Every))
+}
+
+// refill adds the tokens earned since the last update. New buckets start
+// full.
+func refill(s *State, rate Rate, now time.Time) {
+	if s.UpdatedAt.IsZero() {
+		s.Tokens = float64(rate.Burst)
+	} else if elapsed := now.Sub(s.UpdatedAt); elapsed > 0 && rate.Every > 0 {
+		s.Tokens = math.Min(float64(rate.Burst), s.Tokens+float64(elapsed)/float64(rate.Every))
+	}
+	s.UpdatedAt = now
+}
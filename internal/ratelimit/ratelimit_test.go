@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testLimiter(now *time.Time) *Limiter {
+	limiter := New(NewMemoryStore(), Policy{
+		IP:               Rate{Burst: 3, Every: 10 * time.Second},
+		Account:          Rate{Burst: 100, Every: time.Second},
+		LockoutThreshold: 3,
+		LockoutBase:      time.Minute,
+		LockoutMax:       5 * time.Minute,
+		FailureTTL:       time.Hour,
+	})
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	limiter := testLimiter(&now)
+
+	for i := 0; i < 3; i++ {
+		if wait, _ := limiter.AllowIP(ctx, "10.0.0.1"); wait != 0 {
+			t.Fatalf("request %d throttled for %s", i+1, wait)
+		}
+	}
+	if wait, _ := limiter.AllowIP(ctx, "10.0.0.1"); wait != 10*time.Second {
+		t.Fatalf("expected to wait 10s once the burst is used, got %s", wait)
+	}
+	if wait, _ := limiter.AllowIP(ctx, "10.0.0.2"); wait != 0 {
+		t.Fatalf("other IPs should have their own bucket, got %s", wait)
+	}
+
+	now = now.Add(4 * time.Second)
+	if wait, _ := limiter.AllowIP(ctx, "10.0.0.1"); wait != 6*time.Second {
+		t.Fatalf("expected a partly refilled bucket to wait 6s, got %s", wait)
+	}
+	now = now.Add(6 * time.Second)
+	if wait, _ := limiter.AllowIP(ctx, "10.0.0.1"); wait != 0 {
+		t.Fatalf("expected a token after refilling, got %s", wait)
+	}
+}
+
+func TestProgressiveLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	limiter := testLimiter(&now)
+
+	for i, want := range []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		lockout, err := limiter.Failure(ctx, "login:ada@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lockout != want {
+			t.Fatalf("failure %d locked for %s, want %s", i+1, lockout, want)
+		}
+	}
+
+	if wait, _ := limiter.AllowAccount(ctx, "login:ada@example.com"); wait != 5*time.Minute {
+		t.Fatalf("expected locked account to wait 5m, got %s", wait)
+	}
+	now = now.Add(5 * time.Minute)
+	if wait, _ := limiter.AllowAccount(ctx, "login:ada@example.com"); wait != 0 {
+		t.Fatalf("expected lockout to end, got %s", wait)
+	}
+
+	if err := limiter.Success(ctx, "login:ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if lockout, _ := limiter.Failure(ctx, "login:ada@example.com"); lockout != 0 {
+		t.Fatalf("expected success to reset failures, got lockout %s", lockout)
+	}
+}
+
+func TestFailuresExpire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	limiter := testLimiter(&now)
+
+	limiter.Failure(ctx, "2fa:7")
+	limiter.Failure(ctx, "2fa:7")
+	now = now.Add(2 * time.Hour)
+	if lockout, _ := limiter.Failure(ctx, "2fa:7"); lockout != 0 {
+		t.Fatalf("expected old failures to be forgotten, got lockout %s", lockout)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	limiter := testLimiter(&now)
+
+	limiter.AllowIP(ctx, "10.0.0.1")
+	for i := 0; i < 5; i++ {
+		limiter.Failure(ctx, "login:locked@example.com")
+	}
+	now = now.Add(2 * time.Hour)
+
+	purged, err := limiter.Purge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Fatalf("purged %d keys, want 2", purged)
+	}
+}
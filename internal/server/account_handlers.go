@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/export"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/markbates/goth/gothic"
+)
+
+// deleteAccountHandler schedules the caller's account for deletion after
+// ACCOUNT_DELETION_GRACE and logs them out everywhere. Logging in again
+// before then cancels the deletion.
+func (s *Server) deleteAccountHandler(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(int32)
+
+	user, err := s.authService.ScheduleAccountDeletion(c.Request.Context(), userID)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("ScheduleAccountDeletion error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventDeletionScheduled)
+	event.UserID = userID
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	inBackground("Account deletion email", func(ctx context.Context) error {
+		return s.authService.SendAccountDeletionEmail(ctx, user)
+	})
+
+	// The session behind the cookie is gone already.
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		log.Printf("Cookie session error: %v", err)
+	}
+	cookieSession.Options.MaxAge = -1
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Failed to clear session cookie: %v", err)
+	}
+	gothic.Logout(c.Writer, c.Request)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "account scheduled for deletion, log in again before it is deleted to keep it",
+		"deletion_scheduled_at": user.DeletionScheduledAt.Time,
+	})
+}
+
+type dataExportResponse struct {
+	ID           int64      `json:"id"`
+	Status       string     `json:"status"`
+	SizeBytes    int64      `json:"size_bytes,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	DownloadedAt *time.Time `json:"downloaded_at"`
+	DownloadURL  string     `json:"download_url,omitempty"`
+}
+
+func (s *Server) newDataExportResponse(dataExport sqlc.DataExport) dataExportResponse {
+	response := dataExportResponse{
+		ID:          dataExport.ID,
+		Status:      dataExport.Status,
+		SizeBytes:   dataExport.SizeBytes.Int64,
+		CreatedAt:   dataExport.CreatedAt,
+		DownloadURL: s.exportService.DownloadURL(dataExport),
+	}
+	if dataExport.CompletedAt.Valid {
+		response.CompletedAt = &dataExport.CompletedAt.Time
+	}
+	if dataExport.ExpiresAt.Valid {
+		response.ExpiresAt = &dataExport.ExpiresAt.Time
+	}
+	if dataExport.DownloadedAt.Valid {
+		response.DownloadedAt = &dataExport.DownloadedAt.Time
+	}
+	return response
+}
+
+// requestExportHandler queues an archive of everything stored about the
+// caller. The user is emailed a download link once it is built.
+func (s *Server) requestExportHandler(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(int32)
+
+	dataExport, err := s.exportService.Request(c.Request.Context(), userID)
+	if errors.Is(err, export.ErrExportInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Request data export error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request data export"})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventDataExportRequested)
+	event.UserID = userID
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "data export requested, you will get an email once it is ready",
+		"export":  s.newDataExportResponse(dataExport),
+	})
+}
+
+// listExportsHandler shows the caller's exports, with the download link of
+// the one that is ready.
+func (s *Server) listExportsHandler(c *gin.Context) {
+	userID := c.MustGet(middleware.UserIDKey).(int32)
+
+	exports, err := s.exportService.List(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("List data exports error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list data exports"})
+		return
+	}
+
+	response := make([]dataExportResponse, len(exports))
+	for i, dataExport := range exports {
+		response[i] = s.newDataExportResponse(dataExport)
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": response})
+}
+
+// downloadExportHandler serves an archive through its signed link. It needs
+// no login, and each link works once.
+func (s *Server) downloadExportHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": export.ErrLinkInvalid.Error()})
+		return
+	}
+
+	archive, dataExport, err := s.exportService.Open(c.Request.Context(), id, c.Request.URL.Query())
+	switch {
+	case errors.Is(err, export.ErrLinkInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, export.ErrLinkUsed):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Open data export error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to download data export"})
+		return
+	}
+	defer archive.Close()
+
+	filename := fmt.Sprintf("huddle-data-%s.zip", dataExport.CreatedAt.Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Length", strconv.FormatInt(dataExport.SizeBytes.Int64, 10))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// The link only counts as used once the whole archive went out. A
+	// download that broke off can be retried with the same link.
+	written, err := io.Copy(c.Writer, archive)
+	if err != nil || written != dataExport.SizeBytes.Int64 {
+		log.Printf("Data export %d download broke off after %d bytes: %v", dataExport.ID, written, err)
+		inBackground("Release data export", func(ctx context.Context) error {
+			return s.exportService.Release(ctx, dataExport)
+		})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventDataExportDownloaded)
+	event.UserID = dataExport.UserID.Int32
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	inBackground("Discard data export", func(ctx context.Context) error {
+		return s.exportService.Discard(ctx, dataExport)
+	})
+}
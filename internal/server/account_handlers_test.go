@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/export"
+	"huddle-backend/internal/mail"
+	"huddle-backend/internal/ratelimit"
+	"huddle-backend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// TestDownloadExportRoute downloads an archive through /exports/:id the way
+// an emailed link does: without a login, and only once.
+func TestDownloadExportRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, queries := dbtest.New(t)
+	ctx := context.Background()
+
+	previousStore := auth.Store
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("c", 32)))
+	t.Cleanup(func() { auth.Store = previousStore })
+
+	signer, err := storage.NewSigner([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storage.NewMemoryStorage()
+	mailService, err := mail.NewService(mail.NewMemoryMailer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		authService:   auth.NewService(db, nil, mailService, nil),
+		exportService: export.NewService(queries, store, signer, mailService),
+		limiter:       ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.LoadPolicy()),
+	}
+	handler := s.RegisterRoutes()
+
+	user, err := queries.CreatePasswordUser(ctx, sqlc.CreatePasswordUserParams{Username: "ada", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.exportService.Request(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.exportService.BuildPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	exports, err := s.exportService.List(ctx, user.ID)
+	if err != nil || len(exports) != 1 {
+		t.Fatalf("List returned %d exports: %v", len(exports), err)
+	}
+	dataExport := exports[0]
+	link, err := url.Parse(s.exportService.DownloadURL(dataExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	if rec := get("/exports/abc"); rec.Code != http.StatusNotFound {
+		t.Fatalf("malformed id: got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := get(link.Path + "?expires=" + link.Query().Get("expires") + "&signature=forged"); rec.Code != http.StatusNotFound {
+		t.Fatalf("forged signature: got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := get(link.RequestURI())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download: got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/zip" || int64(rec.Body.Len()) != dataExport.SizeBytes.Int64 {
+		t.Fatalf("unexpected download: %s, %d bytes", rec.Header().Get("Content-Type"), rec.Body.Len())
+	}
+
+	if rec := get(link.RequestURI()); rec.Code != http.StatusGone {
+		t.Fatalf("second download: got status %d, want %d", rec.Code, http.StatusGone)
+	}
+
+	// The archive is discarded in the background once it was sent.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		archive, err := store.Open(ctx, dataExport.StorageKey.String)
+		if err == storage.ErrNotFound {
+			break
+		}
+		if err == nil {
+			archive.Close()
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the archive to be discarded after the download")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,11 +1,19 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
+	"time"
 
 	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/middleware"
+	"huddle-backend/internal/profiles"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/mux"
@@ -16,6 +24,33 @@ func (s *Server) beginAuthHandler(c *gin.Context) {
 	provider := c.Param("provider")
 	log.Printf("BeginAuth - Provider: %s", provider)
 
+	s.beginOAuth(c, provider)
+}
+
+// beginOAuth sends the browser to the provider. The optional return_to
+// query parameter names where to land after the callback, and mode=json
+// makes the callback answer with JSON instead of redirecting.
+func (s *Server) beginOAuth(c *gin.Context, provider string) {
+	returnTo := c.Query("return_to")
+	if returnTo != "" {
+		if _, ok := s.redirects.Target(returnTo); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "return_to is not an allowed redirect target"})
+			return
+		}
+	}
+
+	state, err := auth.NewLoginState(returnTo, c.Query("mode") == "json")
+	if err != nil {
+		log.Printf("NewLoginState error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start authentication"})
+		return
+	}
+
+	// gothic reads the state from the query and generates its own otherwise.
+	query := c.Request.URL.Query()
+	query.Set("state", state)
+	c.Request.URL.RawQuery = query.Encode()
+
 	c.Request = setProviderInContext(c.Request, provider)
 	gothic.BeginAuthHandler(c.Writer, c.Request)
 }
@@ -29,28 +64,351 @@ func (s *Server) callbackAuthHandler(c *gin.Context) {
 	gothUser, err := gothic.CompleteUserAuth(c.Writer, c.Request)
 	if err != nil {
 		log.Printf("CompleteUserAuth error: %v", err)
+		event := middleware.AuthEvent(c, auth.EventLoginFailed)
+		event.Provider = provider
+		event.Detail = "provider authentication failed"
+		s.authService.RecordAuthEvent(c.Request.Context(), event)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication failed", "details": err.Error()})
 		return
 	}
 
+	// CompleteUserAuth has checked the state against gothic's cookie.
+	state := auth.ParseLoginState(gothic.GetState(c.Request))
+	target, ok := s.redirects.Target(state.ReturnTo)
+	if !ok {
+		target = s.redirects.Home()
+	}
+
+	if userID, ok := s.pendingIdentityLink(c, provider); ok {
+		if _, err := s.authService.LinkOAuthIdentity(c.Request.Context(), userID, gothUser); err != nil {
+			log.Printf("LinkOAuthIdentity error: %v", err)
+			if errors.Is(err, auth.ErrIdentityInUse) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to connect provider"})
+			return
+		}
+
+		log.Println("=== Identity Linked ===")
+		if state.JSON {
+			c.JSON(http.StatusOK, gin.H{"message": "provider connected", "return_to": target})
+			return
+		}
+		c.Redirect(http.StatusFound, target)
+		return
+	}
+
 	user, err := s.authService.FindOrCreateOAuthUser(c.Request.Context(), gothUser)
+	if errors.Is(err, auth.ErrEmailInUse) {
+		event := middleware.AuthEvent(c, auth.EventLoginFailed)
+		event.Provider = provider
+		event.Email = gothUser.Email
+		event.Detail = "email belongs to an account that is not linked to this provider"
+		s.authService.RecordAuthEvent(c.Request.Context(), event)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, auth.ErrAccountSuspended) {
+		event := middleware.AuthEvent(c, "")
+		event.Provider = provider
+		event.Email = gothUser.Email
+		s.suspendedLogin(c, event, err, !state.JSON)
+		return
+	}
 	if err != nil {
 		log.Printf("FindOrCreateOAuthUser error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save user", "details": err.Error()})
 		return
 	}
 
+	session, err := s.startSession(c, user, provider)
+	if errors.Is(err, auth.ErrAccountSuspended) {
+		event := middleware.AuthEvent(c, "")
+		event.UserID = user.ID
+		event.Provider = provider
+		s.suspendedLogin(c, event, err, !state.JSON)
+		return
+	}
+	if err != nil {
+		log.Printf("startSession error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	if session.MfaPending {
+		log.Println("=== Waiting For Second Factor ===")
+		if state.JSON {
+			c.JSON(http.StatusOK, gin.H{
+				"message":             "second factor required",
+				"two_factor_required": true,
+				"return_to":           target,
+			})
+			return
+		}
+		// The frontend continues to target once the code is accepted.
+		c.Redirect(http.StatusFound, s.redirects.FrontendURL+"/2fa?return_to="+url.QueryEscape(target))
+		return
+	}
+
+	log.Println("=== Authentication Successful ===")
+	event := middleware.AuthEvent(c, auth.EventLoginSucceeded)
+	event.UserID = user.ID
+	event.Provider = provider
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	if state.JSON {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "authentication successful",
+			"user":      userResponse(user),
+			"return_to": target,
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+func (s *Server) registerHandler(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	email, err := auth.NormalizeEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := profile.ValidateUsernameFormat(req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := auth.ValidatePassword(req.Password, email, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := s.authService.RegisterWithPassword(c.Request.Context(), email, req.Username, req.Password)
+	if errors.Is(err, auth.ErrEmailTaken) {
+		// No conflict error, so the form does not confirm who has an
+		// account; the owner of the email is told about the attempt.
+		inBackground("SendAccountExistsEmail", func(ctx context.Context) error {
+			return s.authService.SendAccountExistsEmail(ctx, email)
+		})
+		c.JSON(http.StatusAccepted, gin.H{"message": "check your email to continue"})
+		return
+	}
+	if errors.Is(err, auth.ErrUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("RegisterWithPassword error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register"})
+		return
+	}
+
+	if _, err := s.startSession(c, user, auth.PasswordProvider); err != nil {
+		log.Printf("startSession error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	inBackground("SendEmailVerification", func(ctx context.Context) error {
+		return s.authService.SendEmailVerification(ctx, user.ID)
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "registration successful",
+		"user":    userResponse(user),
+	})
+}
+
+func (s *Server) loginHandler(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	account := "login:" + strings.ToLower(strings.TrimSpace(req.Email))
+	if !s.allowAccount(c, account) {
+		return
+	}
+
+	user, err := s.authService.AuthenticatePassword(c.Request.Context(), req.Email, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		event := middleware.AuthEvent(c, auth.EventLoginFailed)
+		event.Provider = auth.PasswordProvider
+		event.Email = req.Email
+		event.Detail = "invalid email or password"
+		s.loginFailed(c, account, event)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("AuthenticatePassword error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
+		return
+	}
+	s.loginSucceeded(c, account)
+
+	session, err := s.startSession(c, user, auth.PasswordProvider)
+	if errors.Is(err, auth.ErrAccountSuspended) {
+		event := middleware.AuthEvent(c, "")
+		event.UserID = user.ID
+		event.Provider = auth.PasswordProvider
+		s.suspendedLogin(c, event, err, false)
+		return
+	}
+	if err != nil {
+		log.Printf("startSession error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	if session.MfaPending {
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "two-factor authentication required",
+			"two_factor_required": true,
+		})
+		return
+	}
+
+	event := middleware.AuthEvent(c, auth.EventLoginSucceeded)
+	event.UserID = user.ID
+	event.Provider = auth.PasswordProvider
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "login successful",
+		"user":    userResponse(user),
+	})
+}
+
+// forgotPasswordHandler answers the same way whether or not the email has an
+// account. The lookup and the email are handled in the background so the
+// response time does not give it away either.
+func (s *Server) forgotPasswordHandler(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	email, err := auth.NormalizeEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Limits how many reset emails one address can be sent.
+	if !s.allowAccount(c, "reset:"+email) {
+		return
+	}
+
+	inBackground("RequestPasswordReset", func(ctx context.Context) error {
+		return s.authService.RequestPasswordReset(ctx, email)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if an account exists for this email, a reset link has been sent"})
+}
+
+func (s *Server) resetPasswordHandler(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	err := s.authService.ResetPassword(c.Request.Context(), req.Token, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidResetToken), errors.Is(err, auth.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("ResetPassword error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully, please log in again"})
+}
+
+func (s *Server) verifyEmailHandler(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	err := s.authService.VerifyEmail(c.Request.Context(), req.Token)
+	if errors.Is(err, auth.ErrInvalidVerificationToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("VerifyEmail error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+func (s *Server) resendEmailVerificationHandler(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	if c.GetBool(middleware.EmailVerifiedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": auth.ErrEmailAlreadyVerified.Error()})
+		return
+	}
+
+	inBackground("SendEmailVerification", func(ctx context.Context) error {
+		return s.authService.SendEmailVerification(ctx, userID.(int32))
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// startSession creates a sessions row for userID and stores its ID in the
+// huddle_session cookie. Every login method ends here. For users with
+// two-factor authentication the session is pending until verifyTwoFactorHandler
+// succeeds.
+func (s *Server) startSession(c *gin.Context, user sqlc.User, provider string) (sqlc.Session, error) {
 	session, err := s.authService.CreateSession(
 		c.Request.Context(),
-		user.ID,
+		user,
 		provider,
 		c.ClientIP(),
 		c.Request.UserAgent(),
 	)
 	if err != nil {
-		log.Printf("CreateSession error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
-		return
+		return sqlc.Session{}, err
 	}
 
 	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
@@ -60,18 +418,227 @@ func (s *Server) callbackAuthHandler(c *gin.Context) {
 
 	cookieSession.Values[auth.SessionIDKey] = session.ID
 	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
-		log.Printf("Failed to save cookie: %v", err)
+		return sqlc.Session{}, err
+	}
+	return session, nil
+}
+
+// suspendedLogin refuses a login to a suspended account. Browsers coming back
+// from an OAuth provider get an error page instead of JSON.
+func (s *Server) suspendedLogin(c *gin.Context, event auth.AuthEvent, err error, page bool) {
+	event.Type = auth.EventLoginFailed
+	event.Detail = "account suspended"
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	var suspension *auth.SuspendedError
+	if !errors.As(err, &suspension) {
+		suspension = &auth.SuspendedError{}
+	}
+	if page {
+		s.renderSuspendedPage(c, suspension)
+		return
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  suspension.Error(),
+		"code":   "account_suspended",
+		"reason": suspension.Reason,
+		"until":  suspension.Until,
+	})
+}
+
+// verifyTwoFactorHandler completes a login that is waiting for a TOTP or
+// recovery code.
+func (s *Server) verifyTwoFactorHandler(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	sessionID, ok := cookieSession.Values[auth.SessionIDKey].(string)
+	if !ok || sessionID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	pending, err := s.authService.GetSessionByID(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": auth.ErrSessionNotFound.Error()})
+		return
+	}
+	account := fmt.Sprintf("2fa:%d", pending.UserID)
+	if !s.allowAccount(c, account) {
+		return
+	}
+
+	session, err := s.authService.CompleteTwoFactor(c.Request.Context(), sessionID, req.Code)
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		event := middleware.AuthEvent(c, auth.EventLoginFailed)
+		event.UserID = pending.UserID
+		event.Provider = pending.Provider.String
+		event.Detail = "invalid two-factor code"
+		s.loginFailed(c, account, event)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrSessionNotFound), errors.Is(err, auth.ErrNoPendingTwoFactor):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("CompleteTwoFactor error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+
+	cookieSession.Options.MaxAge = int(time.Until(session.ExpiresAt).Seconds())
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Failed to re-issue session cookie: %v", err)
+	}
+
+	s.loginSucceeded(c, account)
+
+	event := middleware.AuthEvent(c, auth.EventLoginSucceeded)
+	event.UserID = session.UserID
+	event.Provider = session.Provider.String
+	event.Detail = "two-factor authentication completed"
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "login successful"})
+}
+
+func userResponse(user sqlc.User) gin.H {
+	return gin.H{
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"avatar_url":     user.AvatarUrl.String,
+		"email_verified": user.EmailVerifiedAt.Valid,
+	}
+}
+
+// inBackground runs fn outside the request so that slow work such as sending
+// email neither delays the response nor reveals anything through its timing.
+func inBackground(name string, fn func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			log.Printf("%s error: %v", name, err)
+		}
+	}()
+}
+
+// connectProviderHandler starts an OAuth flow that links another provider to
+// the logged-in user instead of logging in.
+func (s *Server) connectProviderHandler(c *gin.Context) {
+	provider := c.Param("provider")
+
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session error"})
+		return
+	}
+
+	cookieSession.Values[auth.LinkProviderKey] = provider
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save session"})
 		return
 	}
 
-	log.Println("=== Authentication Successful ===")
-	redirectURL := os.Getenv("FRONTEND_URL") + "/"
-	c.Redirect(http.StatusFound, redirectURL)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "authentication successful",
-		"user":    user,
-	})
+	s.beginOAuth(c, provider)
+}
+
+// pendingIdentityLink consumes a connect flow for provider started by
+// connectProviderHandler and returns the user it was started for, provided
+// their session is still valid.
+func (s *Server) pendingIdentityLink(c *gin.Context, provider string) (int32, bool) {
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		return 0, false
+	}
+
+	linkProvider, ok := cookieSession.Values[auth.LinkProviderKey].(string)
+	if !ok {
+		return 0, false
+	}
+	delete(cookieSession.Values, auth.LinkProviderKey)
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Failed to save cookie: %v", err)
+	}
+	if linkProvider != provider {
+		return 0, false
+	}
+
+	sessionID, ok := cookieSession.Values[auth.SessionIDKey].(string)
+	if !ok || sessionID == "" {
+		return 0, false
+	}
+	sessionData, err := s.authService.GetSessionByID(c.Request.Context(), sessionID)
+	if err != nil || sessionData.MfaPending {
+		return 0, false
+	}
+	return sessionData.UserID, true
+}
+
+// allowAccount applies the per-account rate limit and lockout to account,
+// answering 429 when it is exceeded.
+func (s *Server) allowAccount(c *gin.Context, account string) bool {
+	wait, err := s.limiter.AllowAccount(c.Request.Context(), account)
+	if err != nil {
+		log.Printf("Rate limiter error: %v", err)
+		return true
+	}
+	if wait > 0 {
+		middleware.TooManyRequests(c, wait)
+		return false
+	}
+	return true
+}
+
+// loginFailed records a failed login and counts it toward locking account,
+// recording the lockout as well when it starts one.
+func (s *Server) loginFailed(c *gin.Context, account string, event auth.AuthEvent) {
+	s.authService.RecordAuthEvent(c.Request.Context(), event)
+
+	lockout, err := s.limiter.Failure(c.Request.Context(), account)
+	if err != nil {
+		log.Printf("Rate limiter error: %v", err)
+		return
+	}
+	if lockout > 0 {
+		locked := event
+		locked.Type = auth.EventAccountLocked
+		locked.Detail = fmt.Sprintf("locked for %s after repeated failures: %s", lockout, event.Detail)
+		s.authService.RecordAuthEvent(c.Request.Context(), locked)
+	}
+}
+
+func (s *Server) loginSucceeded(c *gin.Context, account string) {
+	if err := s.limiter.Success(c.Request.Context(), account); err != nil {
+		log.Printf("Rate limiter error: %v", err)
+	}
+}
+
+// csrfTokenHandler hands the SPA the token it must send in the X-CSRF-Token
+// header of unsafe requests made with the session cookie.
+func (s *Server) csrfTokenHandler(c *gin.Context) {
+	token, err := middleware.IssueCSRFToken(c)
+	if err != nil {
+		log.Printf("Failed to issue CSRF token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session error"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"csrf_token": token})
 }
 
 func (s *Server) logoutHandler(c *gin.Context) {
@@ -83,7 +650,12 @@ func (s *Server) logoutHandler(c *gin.Context) {
 
 	sessionID, ok := cookieSession.Values[auth.SessionIDKey].(string)
 	if ok && sessionID != "" {
-
+		if session, err := s.authService.GetSessionByID(c.Request.Context(), sessionID); err == nil {
+			event := middleware.AuthEvent(c, auth.EventLogout)
+			event.UserID = session.UserID
+			event.Provider = session.Provider.String
+			s.authService.RecordAuthEvent(c.Request.Context(), event)
+		}
 		s.authService.DeleteSession(c.Request.Context(), sessionID)
 	}
 
@@ -95,31 +667,136 @@ func (s *Server) logoutHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
 }
 
-func (s *Server) getCurrentUserHandler(c *gin.Context) {
+// exchangeTokenHandler trades the huddle_session cookie for a JWT access
+// token and a refresh token, for clients such as the mobile app that log in
+// through a web view but cannot keep using cookies.
+func (s *Server) exchangeTokenHandler(c *gin.Context) {
 	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
-
 	sessionID, ok := cookieSession.Values[auth.SessionIDKey].(string)
 	if !ok || sessionID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
 		return
 	}
 
-	sessionData, err := s.authService.GetSessionByID(c.Request.Context(), sessionID)
+	pair, err := s.authService.ExchangeSession(c.Request.Context(), sessionID, c.ClientIP(), c.Request.UserAgent())
+	switch {
+	case errors.Is(err, auth.ErrSessionNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "session expired or invalid"})
+		return
+	case errors.Is(err, auth.ErrTwoFactorRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": "two_factor_required"})
+		return
+	case errors.Is(err, auth.ErrImpersonating):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "impersonating"})
+		return
+	case errors.Is(err, auth.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "account_suspended"})
+		return
+	case errors.Is(err, auth.ErrDeletionScheduled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "deletion_scheduled"})
+		return
+	case err != nil:
+		log.Printf("ExchangeSession error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue tokens"})
+		return
+	}
+
+	cookieSession.Options.MaxAge = -1
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Failed to clear session cookie: %v", err)
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+func (s *Server) refreshTokenHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	pair, err := s.authService.RefreshTokens(c.Request.Context(), req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	switch {
+	case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "account_suspended"})
+		return
+	case errors.Is(err, auth.ErrDeletionScheduled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "deletion_scheduled"})
+		return
+	case err != nil:
+		log.Printf("RefreshTokens error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pair)
+}
+
+// revokeTokenHandler logs a token client out. Access tokens already issued
+// stay valid until they expire.
+func (s *Server) revokeTokenHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	err := s.authService.RevokeRefreshToken(c.Request.Context(), req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil && !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		log.Printf("RevokeRefreshToken error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// getCurrentUserHandler works for cookie sessions and access tokens alike, so
+// it reads the user RequireAuth resolved instead of the cookie.
+func (s *Server) getCurrentUserHandler(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	user, err := s.authService.GetUserByID(c.Request.Context(), userID.(int32))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "session expired or invalid"})
 		return
 	}
 
+	// impersonation is null unless an admin is logged in as the user, in
+	// which case the frontend shows a banner.
+	var impersonation *auth.Impersonation
+	if value, ok := c.Get(middleware.ImpersonationKey); ok {
+		i := value.(auth.Impersonation)
+		impersonation = &i
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":         sessionData.ID,
-		"username":   sessionData.Username,
-		"email":      sessionData.Email,
-		"avatar_url": sessionData.AvatarUrl,
-		"provider":   sessionData.Provider,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"avatar_url":     user.AvatarUrl,
+		"provider":       user.Provider,
+		"email_verified": user.EmailVerifiedAt.Valid,
+		"impersonating":  impersonation != nil,
+		"impersonation":  impersonation,
 	})
 }
 
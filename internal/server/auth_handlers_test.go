@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/database/dbtest"
+	"huddle-backend/internal/encryption"
+	"huddle-backend/internal/mail"
+	"huddle-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/gothic"
+)
+
+// mockIssuer is a minimal OpenID Connect provider: discovery, a token
+// endpoint that accepts one code, and a userinfo endpoint.
+func mockIssuer(t *testing.T, claims map[string]any) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "test-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"iss": server.URL,
+			"aud": "huddle",
+			"sub": claims["sub"],
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".sig",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(claims)
+	})
+	return server
+}
+
+// TestOIDCCallback logs in through a configured OpenID Connect provider the
+// way a browser does: /auth/<name>, the provider, then the callback.
+func TestOIDCCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, queries := dbtest.New(t)
+	ctx := context.Background()
+
+	issuer := mockIssuer(t, map[string]any{
+		"sub":                "user-1",
+		"email":              "Ada@Example.com",
+		"email_verified":     true,
+		"name":               "Ada Lovelace",
+		"preferred_username": "ada",
+	})
+	provider, err := auth.NewOIDCProvider(auth.OIDCProviderConfig{
+		Name:           "keycloak",
+		DiscoveryURL:   issuer.URL + "/.well-known/openid-configuration",
+		ClientID:       "huddle",
+		ClientSecret:   "secret",
+		CallbackURL:    "http://api.example/auth/keycloak/callback",
+		Scopes:         []string{"openid", "profile", "email"},
+		EmailClaims:    []string{"email"},
+		NameClaims:     []string{"name"},
+		NickNameClaims: []string{"preferred_username"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goth.UseProviders(provider)
+	t.Cleanup(goth.ClearProviders)
+
+	previousStore, previousGothicStore := auth.Store, gothic.Store
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("c", 32)))
+	gothic.Store = auth.Store
+	t.Cleanup(func() { auth.Store, gothic.Store = previousStore, previousGothicStore })
+
+	keyring, err := encryption.NewKeyring("test", map[string][]byte{"test": []byte(strings.Repeat("e", 32))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailService, err := mail.NewService(mail.NewMemoryMailer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		authService: auth.NewService(db, keyring, mailService, nil),
+		redirects:   auth.RedirectPolicy{FrontendURL: "https://app.example", Paths: []string{"/"}},
+		limiter:     ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.LoadPolicy()),
+	}
+	handler := s.RegisterRoutes()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/keycloak?mode=json", nil))
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("begin: got status %d, want %d: %s", rec.Code, http.StatusTemporaryRedirect, rec.Body.String())
+	}
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), issuer.URL+"/authorize") {
+		t.Fatalf("begin: unexpected redirect to %q", rec.Header().Get("Location"))
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/keycloak/callback?"+url.Values{
+		"code":  {"test-code"},
+		"state": {authURL.Query().Get("state")},
+	}.Encode(), nil)
+	for _, cookie := range rec.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, callback)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback: got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var body struct {
+		Message string `json:"message"`
+		User    struct {
+			Email string `json:"email"`
+		} `json:"user"`
+		ReturnTo string `json:"return_to"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.User.Email != "ada@example.com" || body.ReturnTo != "https://app.example/" {
+		t.Fatalf("callback: unexpected response %s", rec.Body.String())
+	}
+
+	var sessionCookie bool
+	for _, cookie := range rec.Result().Cookies() {
+		sessionCookie = sessionCookie || cookie.Name == auth.SessionName
+	}
+	if !sessionCookie {
+		t.Fatal("callback: expected a session cookie")
+	}
+
+	user, err := queries.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatalf("expected the user to be created: %v", err)
+	}
+	events, err := s.authService.ListAuthEvents(ctx, auth.AuthEventFilter{UserID: user.ID})
+	if err != nil || len(events) != 1 || events[0].EventType != auth.EventLoginSucceeded || events[0].Provider.String != "keycloak" {
+		t.Fatalf("expected a keycloak login to be recorded, got %+v: %v", events, err)
+	}
+}
+
+// TestRegister registers a new email, which logs in, and then the same
+// email again, which only tells its owner.
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, queries := dbtest.New(t)
+	ctx := context.Background()
+
+	previousStore := auth.Store
+	auth.Store = sessions.NewCookieStore([]byte(strings.Repeat("c", 32)))
+	t.Cleanup(func() { auth.Store = previousStore })
+
+	mailer := mail.NewMemoryMailer()
+	mailService, err := mail.NewService(mailer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		authService: auth.NewService(db, nil, mailService, nil),
+		limiter:     ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.LoadPolicy()),
+	}
+	handler := s.RegisterRoutes()
+
+	register := func(email, username string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{
+			"email":    email,
+			"username": username,
+			"password": "correct horse battery staple",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	sessionCookie := func(rec *httptest.ResponseRecorder) bool {
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == auth.SessionName && cookie.MaxAge >= 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	created := register("ada@example.com", "ada")
+	if created.Code != http.StatusCreated || !sessionCookie(created) {
+		t.Fatalf("new email: got status %d, session cookie %v: %s", created.Code, sessionCookie(created), created.Body.String())
+	}
+	user, err := queries.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logins, err := s.authService.ListUserSessions(ctx, user.ID)
+	if err != nil || len(logins) != 1 || logins[0].Provider.String != auth.PasswordProvider {
+		t.Fatalf("expected a password session, got %+v: %v", logins, err)
+	}
+
+	taken := register("Ada@Example.com", "lovelace")
+	if taken.Code != http.StatusAccepted || sessionCookie(taken) || strings.Contains(taken.Body.String(), "ada") {
+		t.Fatalf("taken email: got status %d, session cookie %v: %s", taken.Code, sessionCookie(taken), taken.Body.String())
+	}
+	if user, err := queries.GetUserByEmail(ctx, "ada@example.com"); err != nil || user.Username != "ada" {
+		t.Fatalf("expected the first registration to keep the email, got %q: %v", user.Username, err)
+	}
+
+	// Both emails are sent in the background.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(mailer.Messages()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected two emails, got %+v", mailer.Messages())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	subjects := map[string]bool{}
+	for _, message := range mailer.Messages() {
+		if message.To != "ada@example.com" {
+			t.Fatalf("unexpected email to %s", message.To)
+		}
+		subjects[message.Subject] = true
+	}
+	if !subjects["Confirm your email address"] || !subjects["You already have a Huddle account"] {
+		t.Fatalf("expected a verification and an account exists email, got %v", subjects)
+	}
+
+	if rec := register("grace@example.com", "ada"); rec.Code != http.StatusConflict {
+		t.Fatalf("taken username: got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"huddle-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// suspendedPage is shown when a browser login through an OAuth provider is
+// refused. The callback is a top-level navigation, so a JSON error would
+// leave the user looking at raw text.
+var suspendedPage = template.Must(template.New("suspended").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Account suspended</title>
+</head>
+<body>
+<main>
+<h1>Your account has been suspended</h1>
+{{if .Until}}<p>You can sign in again after {{.Until}}.</p>{{else}}<p>This suspension is permanent.</p>{{end}}
+{{if .Reason}}<p>Reason: {{.Reason}}</p>{{end}}
+<p><a href="{{.Home}}">Back to Huddle</a></p>
+</main>
+</body>
+</html>
+`))
+
+func (s *Server) renderSuspendedPage(c *gin.Context, suspension *auth.SuspendedError) {
+	data := struct {
+		Until  string
+		Reason string
+		Home   string
+	}{
+		Reason: suspension.Reason,
+		Home:   s.redirects.Home(),
+	}
+	if suspension.Until != nil {
+		data.Until = suspension.Until.UTC().Format(time.RFC1123)
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusForbidden)
+	if err := suspendedPage.Execute(c.Writer, data); err != nil {
+		log.Printf("Failed to render suspended page: %v", err)
+	}
+}
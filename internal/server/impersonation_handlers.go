@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"huddle-backend/internal/auth"
+	"huddle-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// impersonateHandler logs the admin in as another user for
+// IMPERSONATION_TTL. The admin's own session is kept in the cookie and comes
+// back when the impersonation ends.
+func (s *Server) impersonateHandler(c *gin.Context) {
+	// The impersonation lives in the huddle_session cookie, which JWT
+	// clients do not have.
+	if c.GetString(middleware.AuthMethodKey) != middleware.AuthMethodSession {
+		c.JSON(http.StatusForbidden, gin.H{"error": "impersonation needs a browser session"})
+		return
+	}
+	adminID := c.MustGet(middleware.UserIDKey).(int32)
+	adminSessionID := c.GetString(middleware.SessionIDKey)
+
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	session, err := s.authService.StartImpersonation(c.Request.Context(), adminID, int32(targetID), c.ClientIP(), c.Request.UserAgent())
+	switch {
+	case errors.Is(err, auth.ErrImpersonateSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	case errors.Is(err, auth.ErrAccountSuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": "suspended users cannot be impersonated"})
+		return
+	case err != nil:
+		log.Printf("StartImpersonation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start impersonation"})
+		return
+	}
+
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		log.Printf("Cookie session error: %v", err)
+	}
+	cookieSession.Values[auth.SessionIDKey] = session.ID
+	cookieSession.Values[auth.ImpersonatorSessionIDKey] = adminSessionID
+	cookieSession.Values[auth.ImpersonatorIDKey] = adminID
+	cookieSession.Values[auth.ImpersonatedUserIDKey] = session.UserID
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		s.authService.DeleteSession(c.Request.Context(), session.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start impersonation"})
+		return
+	}
+
+	action := middleware.AdminAction(c, auth.ActionImpersonationStarted, session.UserID)
+	action.Detail = "until " + session.ExpiresAt.UTC().Format(time.RFC3339)
+	s.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "impersonation started",
+		"impersonation": gin.H{
+			"user_id":    session.UserID,
+			"expires_at": session.ExpiresAt,
+		},
+	})
+}
+
+// endImpersonationHandler ends the impersonation and puts the admin's own
+// session back into the cookie. It goes by the cookie alone, so the admin
+// gets their session back even after the impersonation expired or was
+// revoked.
+func (s *Server) endImpersonationHandler(c *gin.Context) {
+	cookieSession, err := auth.Store.Get(c.Request, auth.SessionName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": auth.ErrNotImpersonating.Error()})
+		return
+	}
+	impersonatorID, userID, ok := auth.CookieImpersonation(cookieSession)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": auth.ErrNotImpersonating.Error()})
+		return
+	}
+
+	if sessionID, _ := cookieSession.Values[auth.SessionIDKey].(string); sessionID != "" {
+		s.authService.DeleteSession(c.Request.Context(), sessionID)
+	}
+
+	adminSessionID, _ := cookieSession.Values[auth.ImpersonatorSessionIDKey].(string)
+	delete(cookieSession.Values, auth.ImpersonatorSessionIDKey)
+	delete(cookieSession.Values, auth.ImpersonatorIDKey)
+	delete(cookieSession.Values, auth.ImpersonatedUserIDKey)
+	cookieSession.Values[auth.SessionIDKey] = adminSessionID
+	if adminSessionID == "" {
+		cookieSession.Options.MaxAge = -1
+	}
+	if err := cookieSession.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Failed to restore session cookie: %v", err)
+	}
+
+	action := middleware.AdminAction(c, auth.ActionImpersonationEnded, userID)
+	action.AdminID = impersonatorID
+	s.authService.RecordAdminAction(c.Request.Context(), action)
+
+	c.JSON(http.StatusOK, gin.H{"message": "impersonation ended"})
+}
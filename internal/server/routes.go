@@ -1,8 +1,11 @@
 package server
 
 import (
+    "log"
     "net/http"
 
+    "huddle-backend/internal/auth"
+    "huddle-backend/internal/config"
     "huddle-backend/internal/handlers"
     "huddle-backend/internal/middleware"
 
@@ -13,41 +16,144 @@ import (
 func (s *Server) RegisterRoutes() http.Handler {
     r := gin.Default()
 
+    // ClientIP feeds rate limits and audit logs, so X-Forwarded-For is only
+    // believed when it comes from a configured proxy.
+    if err := r.SetTrustedProxies(config.List("TRUSTED_PROXIES", nil)); err != nil {
+        log.Fatalf("TRUSTED_PROXIES: %v", err)
+    }
+
+    allowedOrigins := auth.AllowedOrigins()
+
     r.Use(cors.New(cors.Config{
-        AllowOrigins:     []string{"http://localhost:5173"},
+        AllowOrigins:     allowedOrigins,
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-        AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+        AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", middleware.CSRFHeaderName},
         AllowCredentials: true,
     }))
+    r.Use(middleware.CSRF(allowedOrigins))
+    r.Use(middleware.AuditImpersonation(s.authService))
 
     r.GET("/", s.HelloWorldHandler)
     r.GET("/health", s.healthHandler)
 
-    auth := r.Group("/auth")
+    authRoutes := r.Group("/auth", middleware.RateLimitIP(s.limiter))
     {
-        auth.GET("/:provider", s.beginAuthHandler)
-        auth.GET("/:provider/callback", s.callbackAuthHandler)
-        auth.POST("/logout", s.logoutHandler)
+        authRoutes.GET("/csrf", s.csrfTokenHandler)
+        authRoutes.POST("/register", s.registerHandler)
+        authRoutes.POST("/login", s.loginHandler)
+        authRoutes.POST("/password/forgot", s.forgotPasswordHandler)
+        authRoutes.POST("/password/reset", s.resetPasswordHandler)
+        authRoutes.POST("/email/verify", s.verifyEmailHandler)
+        authRoutes.POST("/2fa/verify", s.verifyTwoFactorHandler)
+        authRoutes.POST("/token", s.exchangeTokenHandler)
+        authRoutes.POST("/token/refresh", s.refreshTokenHandler)
+        authRoutes.POST("/token/revoke", s.revokeTokenHandler)
+        authRoutes.GET("/:provider", s.beginAuthHandler)
+        authRoutes.GET("/:provider/callback", s.callbackAuthHandler)
+        authRoutes.POST("/logout", s.logoutHandler)
     }
 
+    // Ending an impersonation must work once it has expired, so it goes by
+    // the cookie rather than RequireAuth.
+    r.POST("/api/impersonation/end", s.endImpersonationHandler)
+
+    // Data export links are signed and work once, so they need no login.
+    r.GET("/exports/:id", middleware.RateLimitIP(s.limiter), s.downloadExportHandler)
+
     profileHandler := handlers.NewProfileHandler(s.profileService)
+    sessionHandler := handlers.NewSessionHandler(s.authService)
+    identityHandler := handlers.NewIdentityHandler(s.authService)
+    twoFactorHandler := handlers.NewTwoFactorHandler(s.authService)
+    tokenHandler := handlers.NewTokenHandler(s.authService)
+    securityHandler := handlers.NewSecurityHandler(s.authService)
+    adminHandler := handlers.NewAdminHandler(s.authService, s.profileService)
 
     api := r.Group("/api")
     api.Use(middleware.RequireAuth(s.authService))
     {
-        api.GET("/me", s.getCurrentUserHandler)
+        api.GET("/me", middleware.RequireScope(auth.ScopeAccountRead), s.getCurrentUserHandler)
+        api.POST("/email/verification", middleware.RequireSession(), s.resendEmailVerificationHandler)
+
+        // Account security settings are only reachable from a browser
+        // session, never with a personal access token.
+        sessions := api.Group("/sessions", middleware.RequireSession())
+        {
+            sessions.GET("", sessionHandler.ListSessions)
+            sessions.POST("/revoke-others", sessionHandler.RevokeOtherSessions)
+            sessions.DELETE("/:id", sessionHandler.RevokeSession)
+        }
 
+        identities := api.Group("/identities", middleware.RequireSession())
+        {
+            identities.GET("", identityHandler.ListIdentities)
+            identities.GET("/connect/:provider", s.connectProviderHandler)
+            identities.DELETE("/:id", identityHandler.UnlinkIdentity)
+        }
+
+        twoFactor := api.Group("/2fa", middleware.RequireSession())
+        {
+            twoFactor.GET("", twoFactorHandler.GetStatus)
+            twoFactor.POST("/enroll", twoFactorHandler.BeginEnrollment)
+            twoFactor.POST("/confirm", twoFactorHandler.ConfirmEnrollment)
+            twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+            twoFactor.DELETE("", twoFactorHandler.Disable)
+        }
+
+        security := api.Group("/security", middleware.RequireSession())
+        {
+            security.GET("/events", securityHandler.ListEvents)
+        }
+
+        tokens := api.Group("/tokens", middleware.RequireSession())
+        {
+            tokens.GET("", tokenHandler.ListTokens)
+            tokens.POST("", tokenHandler.CreateToken)
+            tokens.DELETE("/:id", tokenHandler.RevokeToken)
+        }
+
+        account := api.Group("/account", middleware.RequireSession())
+        {
+            account.DELETE("", s.deleteAccountHandler)
+            account.POST("/export", s.requestExportHandler)
+            account.GET("/exports", s.listExportsHandler)
+        }
+
+        // Roles are read from the session, so these checks cost no query.
+        admin := api.Group("/admin", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionAdminAccess))
+        {
+            auditRead := middleware.RequirePermission(auth.PermissionAuditRead)
+            admin.GET("/auth-events", auditRead, securityHandler.ListAllEvents)
+            admin.GET("/actions", auditRead, adminHandler.ListActions)
+            admin.GET("/roles", adminHandler.ListRoles)
+
+            usersRead := middleware.RequirePermission(auth.PermissionUsersRead)
+            usersWrite := middleware.RequirePermission(auth.PermissionUsersWrite)
+            admin.GET("/users", usersRead, adminHandler.ListUsers)
+            admin.GET("/users/:id", usersRead, adminHandler.GetUser)
+            admin.POST("/users/:id/logout", usersWrite, adminHandler.ForceLogout)
+            admin.POST("/users/:id/suspend", usersWrite, adminHandler.SuspendUser)
+            admin.POST("/users/:id/unsuspend", usersWrite, adminHandler.UnsuspendUser)
+            admin.DELETE("/users/:id", usersWrite, adminHandler.DeleteUser)
+            admin.POST("/users/:id/impersonate", middleware.RequirePermission(auth.PermissionUsersImpersonate), s.impersonateHandler)
+
+            manageRoles := middleware.RequirePermission(auth.PermissionRolesManage)
+            admin.POST("/users/:id/roles", manageRoles, adminHandler.GrantRole)
+            admin.DELETE("/users/:id/roles/:role", manageRoles, adminHandler.RevokeRole)
+        }
+
+        profilesRead := middleware.RequireScope(auth.ScopeProfilesRead)
+        profilesWrite := middleware.RequireScope(auth.ScopeProfilesWrite)
         profiles := api.Group("/profiles")
         {
-            profiles.POST("", profileHandler.CreateProfile)
-            profiles.GET("/me", profileHandler.GetMyProfile)
-            profiles.GET("/check-username", profileHandler.CheckUsernameAvailability)
-            profiles.GET("/search", profileHandler.SearchProfiles)
-            profiles.GET("", profileHandler.ListProfiles)
-            profiles.GET("/:username", profileHandler.GetProfileByUsername)
-            profiles.PUT("", profileHandler.UpdateProfile)
-            profiles.PATCH("/username", profileHandler.UpdateUsername)
-            profiles.DELETE("", profileHandler.DeleteProfile)
+            profiles.POST("", profilesWrite, middleware.RequireVerifiedEmail(), profileHandler.CreateProfile)
+            profiles.GET("/me", profilesRead, profileHandler.GetMyProfile)
+            profiles.GET("/check-username", profilesRead, profileHandler.CheckUsernameAvailability)
+            profiles.GET("/search", profilesRead, profileHandler.SearchProfiles)
+            profiles.GET("", profilesRead, profileHandler.ListProfiles)
+            profiles.GET("/:username", profilesRead, profileHandler.GetProfileByUsername)
+            profiles.PUT("", profilesWrite, profileHandler.UpdateProfile)
+            profiles.PATCH("/username", profilesWrite, profileHandler.UpdateUsername)
+            profiles.DELETE("", profilesWrite, profileHandler.DeleteProfile)
         }
     }
 
@@ -59,5 +165,9 @@ func (s *Server) HelloWorldHandler(c *gin.Context) {
 }
 
 func (s *Server) healthHandler(c *gin.Context) {
-    c.JSON(http.StatusOK, s.db.Health())
+    stats := s.db.Health()
+    for key, value := range s.workers.Health() {
+        stats[key] = value
+    }
+    c.JSON(http.StatusOK, stats)
 }
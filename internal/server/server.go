@@ -2,15 +2,23 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"huddle-backend/internal/auth"
+	"huddle-backend/internal/config"
 	"huddle-backend/internal/database"
 	"huddle-backend/internal/database/sqlc"
+	"huddle-backend/internal/encryption"
+	"huddle-backend/internal/export"
+	"huddle-backend/internal/mail"
 	"huddle-backend/internal/profiles"
+	"huddle-backend/internal/ratelimit"
+	"huddle-backend/internal/storage"
+	"huddle-backend/internal/worker"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -21,9 +29,14 @@ type Server struct {
 	queries        *sqlc.Queries
 	authService    *auth.Service
 	profileService *profile.Service
+	mailService    *mail.Service
+	exportService  *export.Service
+	redirects      auth.RedirectPolicy
+	limiter        *ratelimit.Limiter
+	workers        *worker.Runner
 }
 
-func NewServer() *http.Server {
+func NewServer() (*http.Server, *worker.Runner) {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 
 	db := database.New()
@@ -31,14 +44,91 @@ func NewServer() *http.Server {
 
 	auth.InitAuth()
 
+	keyring, err := encryption.KeyringFromEnv()
+	if err != nil {
+		log.Fatalf("token encryption: %v", err)
+	}
+
+	jwtSigner, err := auth.JWTSignerFromEnv()
+	if err != nil {
+		log.Fatalf("access tokens: %v", err)
+	}
+
+	mailer, err := mail.NewFromEnv()
+	if err != nil {
+		log.Fatalf("mail: %v", err)
+	}
+
+	mailService, err := mail.NewService(mailer)
+	if err != nil {
+		log.Fatalf("mail templates: %v", err)
+	}
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("storage: %v", err)
+	}
+
+	linkSigner, err := storage.SignerFromEnv()
+	if err != nil {
+		log.Fatalf("download links: %v", err)
+	}
+
 	NewServer := &Server{
 		port:           port,
 		db:             db,
 		queries:        queries,
-		authService:    auth.NewService(queries),
+		authService:    auth.NewService(db.DB(), keyring, mailService, jwtSigner),
 		profileService: profile.NewService(queries),
+		mailService:    mailService,
+		exportService:  export.NewService(queries, store, linkSigner, mailService),
+		redirects:      auth.LoadRedirectPolicy(),
+		limiter:        ratelimit.FromEnv(db.DB()),
+		workers:        worker.NewRunner(db.DB()),
 	}
 
+	NewServer.workers.Register(worker.Job{
+		Name:     "session_reaper",
+		Interval: config.Duration("SESSION_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.authService.PurgeExpiredSessions,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "password_reset_reaper",
+		Interval: config.Duration("PASSWORD_RESET_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.authService.PurgeExpiredPasswordResets,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "email_verification_reaper",
+		Interval: config.Duration("EMAIL_VERIFICATION_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.authService.PurgeExpiredEmailVerifications,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "auth_event_reaper",
+		Interval: config.Duration("AUTH_EVENT_REAPER_INTERVAL", 24*time.Hour),
+		Run:      NewServer.authService.PurgeOldAuthEvents,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "account_deletion_reaper",
+		Interval: config.Duration("ACCOUNT_DELETION_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.authService.PurgeDeletedAccounts,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "data_export_builder",
+		Interval: config.Duration("DATA_EXPORT_BUILD_INTERVAL", time.Minute),
+		Run:      NewServer.exportService.BuildPending,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "data_export_reaper",
+		Interval: config.Duration("DATA_EXPORT_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.exportService.Purge,
+	})
+	NewServer.workers.Register(worker.Job{
+		Name:     "rate_limit_reaper",
+		Interval: config.Duration("RATE_LIMIT_REAPER_INTERVAL", time.Hour),
+		Run:      NewServer.limiter.Purge,
+	})
+	NewServer.workers.Start()
+
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
 		Handler:      NewServer.RegisterRoutes(),
@@ -47,5 +137,5 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return server
+	return server, NewServer.workers
 }
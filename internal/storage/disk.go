@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DiskStorage keeps objects as files below a directory. It suits a single
+// instance or a volume shared by all of them.
+type DiskStorage struct {
+	dir string
+}
+
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %w", err)
+	}
+	return &DiskStorage{dir: dir}, nil
+}
+
+// Put writes to a temporary file first, so a failed write never leaves a
+// partial object behind.
+func (d *DiskStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("error storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (d *DiskStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// Delete removes an object. Deleting a missing object is not an error.
+func (d *DiskStorage) Delete(ctx context.Context, key string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// path maps key to a file below the storage directory, refusing keys that
+// would escape it.
+func (d *DiskStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, `\`) || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(key)), nil
+}
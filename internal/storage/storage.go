@@ -0,0 +1,39 @@
+// Package storage keeps files the API hands out later, such as data export
+// archives, behind a small interface so that the backend can be swapped. It
+// also signs the expiring links those files are downloaded through.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores objects under slash-separated keys such as
+// "exports/42/archive.zip". Put replaces an existing object.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv builds the storage named by STORAGE_DRIVER: "disk" (the
+// default, under STORAGE_DIR) or "memory".
+func NewFromEnv() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "disk":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "tmp/storage"
+		}
+		return NewDiskStorage(dir)
+	case "memory":
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
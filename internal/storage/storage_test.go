@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(ctx, "exports/1/archive.zip", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Open(ctx, "exports/1/archive.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" {
+		t.Fatalf("read %q, want %q", data, "hello")
+	}
+
+	if err := store.Delete(ctx, "exports/1/archive.zip"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, "exports/1/archive.zip"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "exports/1/archive.zip"); err != nil {
+		t.Fatalf("deleting a missing object: %v", err)
+	}
+}
+
+func TestDiskStorageRejectsEscapingKeys(t *testing.T) {
+	store, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "..", "../secret", "exports/../../secret", "/etc/passwd", `exports\1`, "exports//1"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestSigner(t *testing.T) {
+	signer, err := NewSigner([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	query := signer.Sign("data_export:42", now.Add(time.Hour))
+
+	if err := signer.Verify("data_export:42", query, now); err != nil {
+		t.Fatalf("expected valid link, got %v", err)
+	}
+	if err := signer.Verify("data_export:43", query, now); err != ErrInvalidSignature {
+		t.Fatalf("expected another resource to be rejected, got %v", err)
+	}
+	if err := signer.Verify("data_export:42", query, now.Add(time.Hour)); err != ErrLinkExpired {
+		t.Fatalf("expected expired link to be rejected, got %v", err)
+	}
+
+	tampered := signer.Sign("data_export:42", now.Add(time.Hour))
+	tampered.Set("expires", "9999999999")
+	if err := signer.Verify("data_export:42", tampered, now); err != ErrInvalidSignature {
+		t.Fatalf("expected extended expiry to be rejected, got %v", err)
+	}
+}
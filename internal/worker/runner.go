@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work that runs on a fixed interval. Run
+// returns the number of rows it affected so the result can be reported
+// through the health endpoint.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+type Status struct {
+	LastRun      time.Time
+	LastAffected int64
+	LastError    string
+	Skipped      bool
+}
+
+// Runner schedules registered jobs. Every run is guarded by a Postgres
+// advisory lock derived from the job name, so when several API instances
+// share a database only one of them runs a given job at a time.
+type Runner struct {
+	db   *sql.DB
+	jobs []Job
+
+	mu     sync.RWMutex
+	status map[string]Status
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(db *sql.DB) *Runner {
+	return &Runner{
+		db:     db,
+		status: make(map[string]Status),
+	}
+}
+
+func (r *Runner) Register(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(ctx, job)
+	}
+}
+
+// Stop cancels all running jobs and waits for them to return, or for ctx to
+// expire.
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop in time: %w", ctx.Err())
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+
+	log.Printf("Worker %s started, interval %s", job.Name, job.Interval)
+	r.runOnce(ctx, job)
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Worker %s stopped", job.Name)
+			return
+		case <-ticker.C:
+			r.runOnce(ctx, job)
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		r.record(job.Name, Status{LastRun: time.Now(), LastError: err.Error()})
+		return
+	}
+	defer conn.Close()
+
+	key := lockKey(job.Name)
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		r.record(job.Name, Status{LastRun: time.Now(), LastError: err.Error()})
+		return
+	}
+	if !acquired {
+		r.record(job.Name, Status{LastRun: time.Now(), Skipped: true})
+		return
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("Worker %s failed to release lock: %v", job.Name, err)
+		}
+	}()
+
+	affected, err := job.Run(ctx)
+	status := Status{LastRun: time.Now(), LastAffected: affected}
+	if err != nil {
+		log.Printf("Worker %s failed: %v", job.Name, err)
+		status.LastError = err.Error()
+	}
+	r.record(job.Name, status)
+}
+
+func (r *Runner) record(name string, status Status) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status[name] = status
+}
+
+// Health reports the outcome of the latest run of every job, keyed by job
+// name so it can be merged into the database health stats.
+func (r *Runner) Health() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]string)
+	for _, job := range r.jobs {
+		status, ok := r.status[job.Name]
+		if !ok {
+			stats[job.Name+"_status"] = "pending"
+			continue
+		}
+
+		stats[job.Name+"_last_run"] = status.LastRun.Format(time.RFC3339)
+		switch {
+		case status.LastError != "":
+			stats[job.Name+"_status"] = "error"
+			stats[job.Name+"_error"] = status.LastError
+		case status.Skipped:
+			stats[job.Name+"_status"] = "skipped, locked by another instance"
+		default:
+			stats[job.Name+"_status"] = "ok"
+			stats[job.Name+"_affected"] = strconv.FormatInt(status.LastAffected, 10)
+		}
+	}
+	return stats
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("huddle-backend/worker/" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"huddle-backend/internal/database/dbtest"
+)
+
+// waitFor polls the status of job until done accepts it.
+func waitFor(t *testing.T, r *Runner, job string, done func(Status) bool) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.RLock()
+		status, ok := r.status[job]
+		r.mu.RUnlock()
+		if ok && done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: gave up waiting, last status %+v", job, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunnerNotStarted(t *testing.T) {
+	r := NewRunner(nil)
+	r.Register(Job{Name: "reaper", Interval: time.Minute})
+
+	if health := r.Health(); health["reaper_status"] != "pending" {
+		t.Fatalf("expected a job that never ran to be pending, got %v", health)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("expected Stop without Start to do nothing, got %v", err)
+	}
+}
+
+func TestRunnerRunsOnInterval(t *testing.T) {
+	db, _ := dbtest.New(t)
+	r := NewRunner(db)
+
+	var runs atomic.Int64
+	r.Register(Job{
+		Name:     "counter",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) (int64, error) {
+			return runs.Add(1), nil
+		},
+	})
+	r.Register(Job{
+		Name:     "broken",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (int64, error) {
+			return 0, errors.New("table is missing")
+		},
+	})
+	r.Start()
+	t.Cleanup(func() { r.Stop(context.Background()) })
+
+	// The first run happens right away, the next ones on the interval.
+	waitFor(t, r, "counter", func(s Status) bool { return s.LastAffected >= 3 })
+	waitFor(t, r, "broken", func(s Status) bool { return s.LastError != "" })
+
+	health := r.Health()
+	if health["counter_status"] != "ok" || health["counter_affected"] == "" || health["counter_last_run"] == "" {
+		t.Errorf("unexpected health for counter: %v", health)
+	}
+	if health["broken_status"] != "error" || health["broken_error"] != "table is missing" {
+		t.Errorf("unexpected health for broken: %v", health)
+	}
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stopped := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Fatal("expected no runs after Stop")
+	}
+}
+
+// TestRunnerSkipsLockedJob holds the job's advisory lock the way another
+// instance running it would.
+func TestRunnerSkipsLockedJob(t *testing.T) {
+	db, _ := dbtest.New(t)
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey("reaper")); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs atomic.Int64
+	r := NewRunner(db)
+	r.Register(Job{
+		Name:     "reaper",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) (int64, error) {
+			return runs.Add(1), nil
+		},
+	})
+	r.Start()
+	t.Cleanup(func() { r.Stop(context.Background()) })
+
+	waitFor(t, r, "reaper", func(s Status) bool { return s.Skipped })
+	if health := r.Health(); health["reaper_status"] != "skipped, locked by another instance" {
+		t.Fatalf("unexpected health %v", health)
+	}
+	if runs.Load() != 0 {
+		t.Fatalf("expected the job not to run while locked, ran %d times", runs.Load())
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey("reaper")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, r, "reaper", func(s Status) bool { return !s.Skipped && s.LastAffected > 0 })
+}
+
+func TestRunnerStopCancelsJobs(t *testing.T) {
+	db, _ := dbtest.New(t)
+	r := NewRunner(db)
+
+	started := make(chan struct{})
+	r.Register(Job{
+		Name:     "slow",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (int64, error) {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	})
+	r.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Stop(ctx); err != nil {
+		t.Fatalf("expected Stop to cancel the running job, got %v", err)
+	}
+	if health := r.Health(); health["slow_status"] != "error" {
+		t.Fatalf("expected the cancelled run to be recorded, got %v", health)
+	}
+}
+
+func TestRunnerStopGivesUp(t *testing.T) {
+	db, _ := dbtest.New(t)
+	r := NewRunner(db)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Register(Job{
+		Name:     "stuck",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (int64, error) {
+			close(started)
+			<-release
+			return 0, nil
+		},
+	})
+	r.Start()
+	<-started
+	defer func() {
+		close(release)
+		r.wg.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Stop to give up on a job ignoring its context, got %v", err)
+	}
+}
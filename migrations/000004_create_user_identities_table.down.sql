@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN access_token TEXT,
+    ADD COLUMN refresh_token TEXT,
+    ADD COLUMN expires_at TIMESTAMP;
+
+UPDATE users u
+SET access_token = i.access_token,
+    refresh_token = i.refresh_token,
+    expires_at = i.expires_at
+FROM user_identities i
+WHERE i.user_id = u.id AND i.provider = u.provider AND i.provider_user_id = u.provider_user_id;
+
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,25 @@
+CREATE TABLE user_identities (
+                                 id SERIAL PRIMARY KEY,
+                                 user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                 provider VARCHAR(50) NOT NULL,
+                                 provider_user_id VARCHAR(255) NOT NULL,
+                                 email VARCHAR(255),
+                                 access_token TEXT,
+                                 refresh_token TEXT,
+                                 expires_at TIMESTAMP,
+                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                 updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                 UNIQUE (provider, provider_user_id)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+INSERT INTO user_identities (user_id, provider, provider_user_id, email, access_token, refresh_token, expires_at, created_at, updated_at)
+SELECT id, provider, provider_user_id, email, access_token, refresh_token, expires_at, created_at, updated_at
+FROM users
+WHERE provider IS NOT NULL AND provider_user_id IS NOT NULL;
+
+ALTER TABLE users
+    DROP COLUMN access_token,
+    DROP COLUMN refresh_token,
+    DROP COLUMN expires_at;
@@ -0,0 +1,23 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- GitHub only returns verified addresses, so accounts whose email came from a
+-- GitHub identity are verified already.
+UPDATE users
+SET email_verified_at = users.created_at
+WHERE EXISTS (
+    SELECT 1 FROM user_identities i
+    WHERE i.user_id = users.id AND i.provider = 'github' AND i.email = users.email
+);
+
+CREATE TABLE email_verification_tokens (
+                                           id SERIAL PRIMARY KEY,
+                                           user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                           email VARCHAR(255) NOT NULL,
+                                           token_hash VARCHAR(64) NOT NULL UNIQUE,
+                                           expires_at TIMESTAMP NOT NULL,
+                                           used_at TIMESTAMP,
+                                           created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+CREATE INDEX idx_email_verification_tokens_expires_at ON email_verification_tokens(expires_at);
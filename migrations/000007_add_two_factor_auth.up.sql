@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT;
+
+ALTER TABLE sessions ADD COLUMN mfa_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE two_factor_recovery_codes (
+                                           id SERIAL PRIMARY KEY,
+                                           user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                           code_hash VARCHAR(64) NOT NULL,
+                                           used_at TIMESTAMP,
+                                           created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                           UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE personal_access_tokens (
+                                        id SERIAL PRIMARY KEY,
+                                        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                        name VARCHAR(100) NOT NULL,
+                                        token_prefix VARCHAR(16) NOT NULL,
+                                        token_hash VARCHAR(64) NOT NULL UNIQUE,
+                                        scopes TEXT NOT NULL,
+                                        last_used_at TIMESTAMP,
+                                        expires_at TIMESTAMP,
+                                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
@@ -0,0 +1,7 @@
+DELETE FROM sessions WHERE kind <> 'cookie';
+
+DROP INDEX IF EXISTS idx_sessions_token_family;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS rotated_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS token_family;
+ALTER TABLE sessions DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,8 @@
+-- Refresh tokens of the token endpoint live next to cookie sessions. Their id
+-- is the hash of the refresh token, and every rotation adds a row to the same
+-- token_family so reuse of an old token can revoke the whole family.
+ALTER TABLE sessions ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'cookie';
+ALTER TABLE sessions ADD COLUMN token_family VARCHAR(64);
+ALTER TABLE sessions ADD COLUMN rotated_at TIMESTAMP;
+
+CREATE INDEX idx_sessions_token_family ON sessions(token_family);
@@ -0,0 +1,17 @@
+-- Audit log of authentication activity. user_id is NULL for failed attempts
+-- on unknown accounts, which keep the attempted email instead.
+CREATE TABLE auth_events (
+                             id BIGSERIAL PRIMARY KEY,
+                             user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                             event_type VARCHAR(50) NOT NULL,
+                             provider VARCHAR(50),
+                             email VARCHAR(255),
+                             ip_address VARCHAR(45),
+                             user_agent TEXT,
+                             detail TEXT,
+                             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_auth_events_user_id ON auth_events(user_id, id);
+CREATE INDEX idx_auth_events_ip_address ON auth_events(ip_address, id);
+CREATE INDEX idx_auth_events_created_at ON auth_events(created_at);
@@ -0,0 +1,11 @@
+-- Token buckets and login failure counters of the Postgres rate limit store.
+-- Keys look like "ip:<address>" or "account:<email or user>".
+CREATE TABLE rate_limits (
+                             key VARCHAR(320) PRIMARY KEY,
+                             tokens DOUBLE PRECISION NOT NULL DEFAULT 0,
+                             failures INTEGER NOT NULL DEFAULT 0,
+                             locked_until TIMESTAMP,
+                             updated_at TIMESTAMP
+);
+
+CREATE INDEX idx_rate_limits_updated_at ON rate_limits(updated_at);
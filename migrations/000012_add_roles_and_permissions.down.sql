@@ -0,0 +1,7 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS permissions;
+ALTER TABLE sessions DROP COLUMN IF EXISTS roles;
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
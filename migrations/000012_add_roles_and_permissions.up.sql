@@ -0,0 +1,52 @@
+CREATE TABLE roles (
+                       id SERIAL PRIMARY KEY,
+                       name VARCHAR(50) NOT NULL UNIQUE,
+                       description TEXT,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE permissions (
+                             id SERIAL PRIMARY KEY,
+                             name VARCHAR(100) NOT NULL UNIQUE,
+                             description TEXT
+);
+
+CREATE TABLE role_permissions (
+                                  role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+                                  permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+                                  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+                            user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                            role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+                            granted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                            PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_role_id ON user_roles(role_id);
+
+-- Sessions cache the roles and permissions of their user, space separated, so
+-- that RequireAuth can authorize without another query. Granting or revoking
+-- a role rewrites them.
+ALTER TABLE sessions ADD COLUMN roles TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN permissions TEXT NOT NULL DEFAULT '';
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to the admin API'),
+    ('support', 'Reads users and the audit log');
+
+INSERT INTO permissions (name, description) VALUES
+    ('admin:access', 'Use the admin API'),
+    ('users:read', 'View users, their sessions and profiles'),
+    ('users:write', 'Log out, suspend and delete users'),
+    ('audit:read', 'Read the authentication audit log'),
+    ('roles:manage', 'Grant and revoke roles');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         CROSS JOIN permissions p
+WHERE r.name = 'admin'
+   OR (r.name = 'support' AND p.name IN ('admin:access', 'users:read', 'audit:read'));
@@ -0,0 +1,18 @@
+-- Audit trail of administrator actions. Both users are SET NULL on delete so
+-- the trail outlives them; target_email records who a deleted user was.
+CREATE TABLE admin_actions (
+                               id BIGSERIAL PRIMARY KEY,
+                               admin_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                               target_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                               target_email VARCHAR(255),
+                               action VARCHAR(50) NOT NULL,
+                               detail TEXT,
+                               ip_address VARCHAR(45),
+                               user_agent TEXT,
+                               created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_admin_actions_admin_id ON admin_actions(admin_id, id);
+CREATE INDEX idx_admin_actions_target_user_id ON admin_actions(target_user_id, id);
+
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
@@ -0,0 +1,4 @@
+-- A suspended account has suspended_at set. It stays suspended until
+-- suspended_until, or for good when that is NULL.
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name = 'users:impersonate';
+
+DROP INDEX IF EXISTS idx_sessions_impersonator_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS impersonator_id;
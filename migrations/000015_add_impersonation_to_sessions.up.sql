@@ -0,0 +1,16 @@
+-- An impersonation session logs impersonator_id in as user_id. It carries no
+-- roles, never renews, and ends with the impersonator's account.
+ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_sessions_impersonator_id ON sessions(impersonator_id) WHERE impersonator_id IS NOT NULL;
+
+-- Only admins may impersonate by default. Support reads users and the audit
+-- log; operators who want support staff to impersonate grant it on purpose.
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Log in as another user');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+         CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'users:impersonate';
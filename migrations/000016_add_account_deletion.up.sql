@@ -0,0 +1,5 @@
+-- An account with deletion_scheduled_at set is hidden and gets deleted for
+-- good once that time passes, unless the user logs in again first.
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- A data export is queued as pending, built by a background job and then
+-- ready for one download until expires_at. Exports outlive their user only
+-- until the job has removed the archive.
+CREATE TABLE data_exports (
+                              id BIGSERIAL PRIMARY KEY,
+                              user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                              status VARCHAR(20) NOT NULL DEFAULT 'pending',
+                              storage_key TEXT,
+                              size_bytes BIGINT,
+                              expires_at TIMESTAMP,
+                              downloaded_at TIMESTAMP,
+                              completed_at TIMESTAMP,
+                              created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+CREATE UNIQUE INDEX idx_data_exports_pending ON data_exports(user_id) WHERE status = 'pending';
@@ -0,0 +1,34 @@
+-- name: CreateAdminAction :exec
+INSERT INTO admin_actions (
+    admin_id,
+    target_user_id,
+    target_email,
+    action,
+    detail,
+    ip_address,
+    user_agent
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAdminActions :many
+SELECT * FROM admin_actions
+WHERE (sqlc.narg(admin_id)::integer IS NULL OR admin_id = sqlc.narg(admin_id))
+  AND (sqlc.narg(target_user_id)::integer IS NULL OR target_user_id = sqlc.narg(target_user_id))
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ScrubAdminActionTarget :exec
+UPDATE admin_actions
+SET target_user_id = NULL, target_email = NULL
+WHERE target_user_id = $1;
+
+-- name: ScrubAdminActionActor :exec
+UPDATE admin_actions
+SET admin_id = NULL, ip_address = NULL, user_agent = NULL
+WHERE admin_id = $1;
+
+-- name: ListUserAdminActions :many
+SELECT * FROM admin_actions
+WHERE target_user_id = $1
+ORDER BY id;
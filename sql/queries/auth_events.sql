@@ -0,0 +1,35 @@
+-- name: CreateAuthEvent :exec
+INSERT INTO auth_events (
+    user_id,
+    event_type,
+    provider,
+    email,
+    ip_address,
+    user_agent,
+    detail
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAuthEvents :many
+SELECT * FROM auth_events
+WHERE (sqlc.narg(user_id)::integer IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(ip_address)::text IS NULL OR ip_address = sqlc.narg(ip_address))
+  AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until))
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteAuthEventsBefore :execrows
+DELETE FROM auth_events
+WHERE created_at < $1;
+
+-- name: ScrubUserAuthEvents :exec
+UPDATE auth_events
+SET user_id = NULL, email = NULL, ip_address = NULL, user_agent = NULL, detail = NULL
+WHERE user_id = $1 OR email = $2;
+
+-- name: ListUserAuthEvents :many
+SELECT * FROM auth_events
+WHERE user_id = $1
+ORDER BY id;
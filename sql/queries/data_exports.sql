@@ -0,0 +1,56 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING *;
+
+-- name: ListUserDataExports :many
+SELECT * FROM data_exports
+WHERE user_id = $1
+ORDER BY id DESC;
+
+-- name: ListPendingDataExports :many
+SELECT * FROM data_exports
+WHERE status = 'pending' AND user_id IS NOT NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: CompleteDataExport :execrows
+UPDATE data_exports
+SET status = 'ready', storage_key = $2, size_bytes = $3, expires_at = $4, completed_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', completed_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1;
+
+-- name: ClaimDataExportDownload :one
+UPDATE data_exports
+SET status = 'downloaded', downloaded_at = NOW()
+WHERE id = $1 AND status = 'ready' AND expires_at > NOW() AND user_id IS NOT NULL
+RETURNING *;
+
+-- name: ReleaseDataExportDownload :exec
+UPDATE data_exports
+SET status = 'ready', downloaded_at = NULL
+WHERE id = $1 AND status = 'downloaded' AND storage_key IS NOT NULL;
+
+-- name: ListDataExportsToPurge :many
+SELECT * FROM data_exports
+WHERE user_id IS NULL
+   OR (storage_key IS NOT NULL AND (status = 'downloaded' OR expires_at <= NOW()))
+ORDER BY id
+LIMIT $1;
+
+-- name: ClearDataExportStorage :exec
+UPDATE data_exports
+SET storage_key = NULL
+WHERE id = $1;
+
+-- name: DeleteDataExport :exec
+DELETE FROM data_exports
+WHERE id = $1;
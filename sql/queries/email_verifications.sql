@@ -0,0 +1,28 @@
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (
+    user_id,
+    email,
+    token_hash,
+    expires_at
+)
+VALUES ($1, $2, $3, $4)
+    RETURNING *;
+
+-- name: ConsumeEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+    RETURNING *;
+
+-- name: DeleteUserEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens
+WHERE user_id = $1;
+
+-- name: DeleteExpiredEmailVerificationTokens :execrows
+DELETE FROM email_verification_tokens
+WHERE expires_at < NOW() OR used_at IS NOT NULL;
+
+-- name: ListUserEmailVerificationTokens :many
+SELECT * FROM email_verification_tokens
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,42 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+    user_id,
+    provider,
+    provider_user_id,
+    email,
+    access_token,
+    refresh_token,
+    expires_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING *;
+
+-- name: GetUserIdentityByProvider :one
+SELECT * FROM user_identities
+WHERE provider = $1 AND provider_user_id = $2;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateUserIdentityTokens :one
+UPDATE user_identities
+SET
+    email = $2,
+    access_token = $3,
+    refresh_token = $4,
+    expires_at = $5,
+    updated_at = NOW()
+WHERE id = $1
+    RETURNING *;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListUserIdentitiesWithOAuthTokens :many
+SELECT * FROM user_identities
+WHERE id > $1 AND (access_token IS NOT NULL OR refresh_token IS NOT NULL)
+ORDER BY id
+    LIMIT $2;
@@ -0,0 +1,39 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (
+    user_id,
+    name,
+    token_prefix,
+    token_hash,
+    scopes,
+    expires_at
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT t.*, u.email_verified_at, u.suspended_at, u.suspended_until, u.deletion_scheduled_at
+FROM personal_access_tokens t
+         JOIN users u ON t.user_id = u.id
+WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > NOW());
+
+-- name: ListUserPersonalAccessTokens :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: CountUserPersonalAccessTokens :one
+SELECT COUNT(*) FROM personal_access_tokens
+WHERE user_id = $1;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE FROM personal_access_tokens
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteUserPersonalAccessTokens :exec
+DELETE FROM personal_access_tokens
+WHERE user_id = $1;
@@ -47,12 +47,26 @@ WHERE user_id = $1;
 
 -- name: ListProfiles :many
 SELECT * FROM profiles
+WHERE NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = profiles.user_id
+      AND (users.deletion_scheduled_at IS NOT NULL
+        OR (users.suspended_at IS NOT NULL
+          AND (users.suspended_until IS NULL OR users.suspended_until > NOW())))
+)
 ORDER BY created_at DESC
     LIMIT $1 OFFSET $2;
 
 -- name: SearchProfilesByUsername :many
 SELECT * FROM profiles
 WHERE username ILIKE $1
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = profiles.user_id
+      AND (users.deletion_scheduled_at IS NOT NULL
+        OR (users.suspended_at IS NOT NULL
+          AND (users.suspended_until IS NULL OR users.suspended_until > NOW())))
+  )
 ORDER BY username
     LIMIT $2 OFFSET $3;
 
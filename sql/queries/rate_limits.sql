@@ -0,0 +1,19 @@
+-- name: CreateRateLimit :exec
+INSERT INTO rate_limits (key)
+VALUES ($1)
+ON CONFLICT (key) DO NOTHING;
+
+-- name: GetRateLimitForUpdate :one
+SELECT tokens, failures, locked_until, updated_at FROM rate_limits
+WHERE key = $1
+FOR UPDATE;
+
+-- name: UpdateRateLimit :exec
+UPDATE rate_limits
+SET tokens = $2, failures = $3, locked_until = $4, updated_at = $5
+WHERE key = $1;
+
+-- name: PurgeRateLimits :execrows
+DELETE FROM rate_limits
+WHERE (updated_at IS NULL OR updated_at < sqlc.arg(idle_since))
+  AND (locked_until IS NULL OR locked_until <= sqlc.arg(now));
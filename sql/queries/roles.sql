@@ -0,0 +1,58 @@
+-- name: ListRoles :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: GetRoleByName :one
+SELECT * FROM roles
+WHERE name = $1;
+
+-- name: LockRoleByName :one
+SELECT * FROM roles
+WHERE name = $1
+FOR UPDATE;
+
+-- name: ListRolePermissions :many
+SELECT rp.role_id, p.name
+FROM role_permissions rp
+         JOIN permissions p ON rp.permission_id = p.id
+ORDER BY p.name;
+
+-- name: GetUserRoleNames :many
+SELECT r.name
+FROM user_roles ur
+         JOIN roles r ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+
+-- name: GetUserPermissionNames :many
+SELECT DISTINCT p.name
+FROM user_roles ur
+         JOIN role_permissions rp ON ur.role_id = rp.role_id
+         JOIN permissions p ON rp.permission_id = p.id
+WHERE ur.user_id = $1
+ORDER BY p.name;
+
+-- name: GrantUserRole :execrows
+INSERT INTO user_roles (user_id, role_id, granted_by)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RevokeUserRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
+
+-- name: CountRoleMembers :one
+SELECT COUNT(*) FROM user_roles
+WHERE role_id = $1;
+
+-- name: UpdateUserSessionGrants :exec
+UPDATE sessions
+SET roles = $2, permissions = $3
+WHERE user_id = $1 AND impersonator_id IS NULL;
+
+-- name: ListUserRoleGrants :many
+SELECT r.name, ur.created_at
+FROM user_roles ur
+         JOIN roles r ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
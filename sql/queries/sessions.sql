@@ -5,27 +5,90 @@ INSERT INTO sessions (
     provider,
     ip_address,
     user_agent,
-    expires_at
+    expires_at,
+    mfa_pending,
+    roles,
+    permissions
 )
-VALUES ($1, $2, $3, $4, $5, $6)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
     RETURNING *;
 
 -- name: GetSessionByID :one
 SELECT s.*, u.*
 FROM sessions s
          JOIN users u ON s.user_id = u.id
-WHERE s.id = $1 AND s.expires_at > NOW();
+WHERE s.id = $1 AND s.kind = 'cookie' AND s.expires_at > NOW();
 
 -- name: DeleteSession :exec
 DELETE FROM sessions WHERE id = $1;
 
 -- name: DeleteUserSessions :exec
-DELETE FROM sessions WHERE user_id = $1;
+DELETE FROM sessions WHERE user_id = $1 OR impersonator_id = $1;
+
+-- name: DeleteImpersonatorSessions :exec
+DELETE FROM sessions WHERE impersonator_id = $1;
 
--- name: DeleteExpiredSessions :exec
+-- name: DeleteExpiredSessions :execrows
 DELETE FROM sessions WHERE expires_at < NOW();
 
 -- name: GetUserSessions :many
 SELECT * FROM sessions
-WHERE user_id = $1 AND expires_at > NOW()
+WHERE user_id = $1 AND expires_at > NOW() AND rotated_at IS NULL
+  AND impersonator_id IS NULL AND NOT mfa_pending
 ORDER BY created_at DESC;
+
+-- name: DeleteOtherUserSessions :execrows
+DELETE FROM sessions
+WHERE user_id = $1 AND id <> $2 AND (token_family IS NULL OR token_family <> $2)
+  AND impersonator_id IS NULL;
+
+-- name: ExtendSession :exec
+UPDATE sessions
+SET expires_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CompleteSessionMFA :execrows
+UPDATE sessions
+SET mfa_pending = FALSE, expires_at = $2, updated_at = NOW()
+WHERE id = $1 AND mfa_pending = TRUE AND expires_at > NOW();
+
+-- name: CreateRefreshSession :one
+INSERT INTO sessions (
+    id,
+    user_id,
+    provider,
+    ip_address,
+    user_agent,
+    expires_at,
+    kind,
+    token_family,
+    created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING *;
+
+-- name: GetRefreshSession :one
+SELECT * FROM sessions
+WHERE id = $1 AND kind = 'refresh';
+
+-- name: RotateRefreshSession :execrows
+UPDATE sessions
+SET rotated_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND rotated_at IS NULL AND expires_at > NOW();
+
+-- name: DeleteSessionFamily :execrows
+DELETE FROM sessions
+WHERE token_family = $1;
+
+-- name: CreateImpersonationSession :one
+INSERT INTO sessions (
+    id,
+    user_id,
+    impersonator_id,
+    provider,
+    ip_address,
+    user_agent,
+    expires_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING *;
@@ -0,0 +1,50 @@
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: AdvanceUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg(step)
+WHERE id = sqlc.arg(id) AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg(step));
+
+-- name: ListUsersWithTOTPSecret :many
+SELECT id, totp_secret FROM users
+WHERE totp_secret IS NOT NULL AND id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO two_factor_recovery_codes (
+    user_id,
+    code_hash
+)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE two_factor_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM two_factor_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM two_factor_recovery_codes
+WHERE user_id = $1;
+
+-- name: ListUserRecoveryCodes :many
+SELECT * FROM two_factor_recovery_codes
+WHERE user_id = $1
+ORDER BY created_at;
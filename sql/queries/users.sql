@@ -5,17 +5,15 @@ INSERT INTO users (
     avatar_url,
     provider,
     provider_user_id,
-    access_token,
-    refresh_token,
-    expires_at,
     name,
     first_name,
     last_name,
     nick_name,
     description,
-    location
+    location,
+    email_verified_at
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
     RETURNING *;
 
 -- name: GetUserByProviderID :one
@@ -26,24 +24,29 @@ WHERE provider = $1 AND provider_user_id = $2;
 SELECT * FROM users
 WHERE id = $1;
 
+-- name: LockUserByID :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1;
 
--- name: UpdateUserOAuthTokens :one
-UPDATE users
-SET
-    access_token = $2,
-    refresh_token = $3,
-    expires_at = $4,
-    updated_at = NOW()
-WHERE id = $1
-    RETURNING *;
-
 -- name: ListUsers :many
 SELECT * FROM users
-ORDER BY created_at DESC
-    LIMIT $1 OFFSET $2;
+WHERE (sqlc.narg(email_pattern)::text IS NULL OR email ILIKE sqlc.narg(email_pattern))
+  AND (sqlc.narg(username_pattern)::text IS NULL OR username ILIKE sqlc.narg(username_pattern))
+  AND (sqlc.narg(provider)::text IS NULL
+    OR provider = sqlc.narg(provider)
+    OR (sqlc.narg(provider)::text = 'password' AND password_hash IS NOT NULL)
+    OR EXISTS (
+        SELECT 1 FROM user_identities
+        WHERE user_identities.user_id = users.id AND user_identities.provider = sqlc.narg(provider)
+    ))
+  AND (sqlc.narg(before_id)::integer IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
 
 -- name: UpdateUser :one
 UPDATE users
@@ -54,3 +57,56 @@ WHERE id = $1
 -- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1;
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), suspended_until = $2, suspension_reason = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL, updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NOT NULL;
+
+-- name: GetUserSuspension :one
+SELECT suspended_at, suspended_until, suspension_reason, deletion_scheduled_at FROM users
+WHERE id = $1;
+
+-- name: CreatePasswordUser :one
+INSERT INTO users (
+    username,
+    email,
+    password_hash
+)
+VALUES ($1, $2, $3)
+    RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :execrows
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL;
+
+-- name: ScheduleUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL;
+
+-- name: ListUsersDueForDeletion :many
+SELECT id, email FROM users
+WHERE deletion_scheduled_at <= NOW()
+ORDER BY deletion_scheduled_at
+LIMIT $1;
+
+-- name: DeleteScheduledUser :execrows
+DELETE FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW();